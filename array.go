@@ -0,0 +1,207 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// defaultArrayIndexInterval is the number of elements between two indexed
+// offsets, for var-length elements.
+const defaultArrayIndexInterval = 16
+
+// arrayHeaderSize is the size of the header of a serialized Array:
+// element count, index interval and data size, each is an uint32.
+const arrayHeaderSize = 12
+
+var (
+	// ErrInvalidInterval indicates an index interval is not a positive number.
+	ErrInvalidInterval = errors.New("index interval must be positive")
+	// ErrMalformed indicates a serialized data structure is broken.
+	ErrMalformed = errors.New("malformed data")
+)
+
+// Array is a read-only array of encoded elements stored contiguously in one
+// byte slice.
+// Get(i) is O(1) if the Codec has fixed size.
+// Otherwise Array keeps a sparse index of the offsets of every
+// "interval"-th element, and Get(i) has to skip at most interval-1 elements.
+//
+// Serialized Array is in the following layout, integers are little endian
+// uint32:
+//
+//     n        // number of elements
+//     interval // 0 if elements are fixed size
+//     size     // size of data
+//     index    // ceil(n/interval) offsets in data; absent if interval is 0
+//     data     // encoded elements
+type Array struct {
+	codec    Codec
+	n        int
+	fixed    bool
+	eltSize  int
+	interval int
+	index    []byte
+	data     []byte
+}
+
+// NewArray creates an *Array from a slice "elts" with Codec c.
+// Every element in "elts" must be acceptable to c.
+// An optional "interval" specifies the number of elements between two indexed
+// offsets, it is ignored if c is fixed size.
+func NewArray(c Codec, elts interface{}, interval ...int) (*Array, error) {
+
+	sl := reflect.ValueOf(elts)
+	if sl.Kind() != reflect.Slice {
+		return nil, ErrNotSlice
+	}
+
+	a := &Array{
+		codec: c,
+		n:     sl.Len(),
+	}
+
+	a.eltSize, a.fixed = fixedSize(c)
+	if !a.fixed {
+		a.interval = defaultArrayIndexInterval
+		if len(interval) > 0 {
+			a.interval = interval[0]
+		}
+		if a.interval <= 0 {
+			return nil, errors.Wrapf(ErrInvalidInterval, "interval: %d", a.interval)
+		}
+		a.index = make([]byte, 0, (a.n+a.interval-1)/a.interval*4)
+	}
+
+	for i := 0; i < a.n; i++ {
+		if !a.fixed && i%a.interval == 0 {
+			a.index = appendU32(a.index, len(a.data))
+		}
+		a.data = append(a.data, c.Encode(sl.Index(i).Interface())...)
+	}
+
+	return a, nil
+}
+
+// UnmarshalArray loads an *Array from bytes built by Array.Marshal.
+// The data is not copied: the returned Array refers to b.
+// It returns the Array and the number of bytes consumed.
+//
+// c must be the same Codec used to build the Array.
+func UnmarshalArray(c Codec, b []byte) (*Array, int, error) {
+
+	if len(b) < arrayHeaderSize {
+		return nil, 0, errors.Wrapf(ErrShortBuffer, "header: need: %d, got: %d", arrayHeaderSize, len(b))
+	}
+
+	a := &Array{
+		codec:    c,
+		n:        int(binary.LittleEndian.Uint32(b)),
+		interval: int(binary.LittleEndian.Uint32(b[4:])),
+	}
+	dataSize := int(binary.LittleEndian.Uint32(b[8:]))
+
+	a.eltSize, a.fixed = fixedSize(c)
+
+	indexSize := 0
+	if a.fixed {
+		if a.interval != 0 {
+			return nil, 0, errors.Wrapf(ErrMalformed, "fixed size element with interval: %d", a.interval)
+		}
+		if dataSize != a.n*a.eltSize {
+			return nil, 0, errors.Wrapf(ErrMalformed, "data size: %d, want: %d", dataSize, a.n*a.eltSize)
+		}
+	} else {
+		if a.interval <= 0 {
+			return nil, 0, errors.Wrapf(ErrInvalidInterval, "interval: %d", a.interval)
+		}
+		indexSize = (a.n + a.interval - 1) / a.interval * 4
+	}
+
+	total := arrayHeaderSize + indexSize + dataSize
+	if len(b) < total {
+		return nil, 0, errors.Wrapf(ErrShortBuffer, "need: %d, got: %d", total, len(b))
+	}
+
+	a.index = b[arrayHeaderSize : arrayHeaderSize+indexSize]
+	a.data = b[arrayHeaderSize+indexSize : total]
+
+	prev := 0
+	for i := 0; i < len(a.index); i += 4 {
+		off := int(binary.LittleEndian.Uint32(a.index[i:]))
+		if off < prev || off > dataSize || (i == 0 && off != 0) {
+			return nil, 0, errors.Wrapf(ErrMalformed, "index %d: offset: %d, previous: %d, data size: %d", i/4, off, prev, dataSize)
+		}
+		prev = off
+	}
+
+	return a, total, nil
+}
+
+// Marshal serializes the Array into a byte slice.
+func (a *Array) Marshal() []byte {
+	b := make([]byte, 0, arrayHeaderSize+len(a.index)+len(a.data))
+	b = appendU32(b, a.n)
+	b = appendU32(b, a.interval)
+	b = appendU32(b, len(a.data))
+	b = append(b, a.index...)
+	b = append(b, a.data...)
+	return b
+}
+
+// Len returns the number of elements.
+func (a *Array) Len() int {
+	return a.n
+}
+
+// Get returns the i-th element.
+// It panics if i is out of range.
+func (a *Array) Get(i int) interface{} {
+	if i < 0 || i >= a.n {
+		panic(errors.Errorf("index out of range: %d, len: %d", i, a.n))
+	}
+
+	_, v := a.codec.Decode(a.data[a.offset(i):])
+	return v
+}
+
+// Range calls f with every element in order, until f returns false.
+func (a *Array) Range(f func(i int, v interface{}) bool) {
+	off := 0
+	for i := 0; i < a.n; i++ {
+		n, v := a.codec.Decode(a.data[off:])
+		if !f(i, v) {
+			return
+		}
+		off += n
+	}
+}
+
+// offset returns the offset in data of the i-th element.
+func (a *Array) offset(i int) int {
+	if a.fixed {
+		return i * a.eltSize
+	}
+
+	start := i / a.interval
+	off := int(binary.LittleEndian.Uint32(a.index[start*4:]))
+	for j := start * a.interval; j < i; j++ {
+		off += a.codec.EncodedSize(a.data[off:])
+	}
+	return off
+}
+
+func appendU16(b []byte, v uint16) []byte {
+	return append(b, byte(v), byte(v>>8))
+}
+
+func appendU32(b []byte, v int) []byte {
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendU64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
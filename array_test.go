@@ -0,0 +1,156 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArray(t *testing.T) {
+
+	ta := require.New(t)
+
+	xy, err := NewTypeCodec(typeXY{})
+	ta.NoError(err)
+
+	strs := []string{}
+	for i := 0; i < 100; i++ {
+		strs = append(strs, fmt.Sprintf("%d", i*i))
+	}
+
+	cases := []struct {
+		codec    Codec
+		input    interface{}
+		interval []int
+		want     []interface{}
+	}{
+		{U32{}, []uint32{}, nil, []interface{}{}},
+		{U32{}, []uint32{1, 2, 3}, nil, []interface{}{uint32(1), uint32(2), uint32(3)}},
+		{I16{}, []int16{-1, 5}, []int{3}, []interface{}{int16(-1), int16(5)}},
+		{xy, []typeXY{{1, 2}, {3, 4}}, nil, []interface{}{typeXY{1, 2}, typeXY{3, 4}}},
+		{String16{}, []string{"a", "", "bcd"}, nil, []interface{}{"a", "", "bcd"}},
+		{String16{}, strs, nil, nil},
+		{String16{}, strs, []int{1}, nil},
+		{String16{}, strs, []int{7}, nil},
+	}
+
+	for i, c := range cases {
+		want := c.want
+		if want == nil {
+			for _, s := range strs {
+				want = append(want, s)
+			}
+		}
+
+		a, err := NewArray(c.codec, c.input, c.interval...)
+		ta.NoError(err)
+
+		b := a.Marshal()
+		b = append(b, 'x')
+		loaded, n, err := UnmarshalArray(c.codec, b)
+		ta.NoError(err)
+		ta.Equal(len(b)-1, n, "%d-th: case: %+v", i+1, c)
+
+		for _, arr := range []*Array{a, loaded} {
+			ta.Equal(len(want), arr.Len())
+
+			for j, w := range want {
+				ta.Equal(w, arr.Get(j), "%d-th: idx: %d", i+1, j)
+			}
+
+			var got []interface{}
+			arr.Range(func(j int, v interface{}) bool {
+				ta.Equal(len(got), j)
+				got = append(got, v)
+				return true
+			})
+			if len(want) > 0 {
+				ta.Equal(want, got)
+			}
+
+			testPanic(t, func() { arr.Get(-1) }, "negative index")
+			testPanic(t, func() { arr.Get(len(want)) }, "index out of range")
+		}
+	}
+}
+
+func TestArrayRangeStop(t *testing.T) {
+
+	ta := require.New(t)
+
+	a, err := NewArray(U8{}, []uint8{1, 2, 3})
+	ta.NoError(err)
+
+	cnt := 0
+	a.Range(func(i int, v interface{}) bool {
+		cnt++
+		return i < 1
+	})
+	ta.Equal(2, cnt)
+}
+
+func TestArrayError(t *testing.T) {
+
+	ta := require.New(t)
+
+	_, err := NewArray(U8{}, 1)
+	ta.Equal(ErrNotSlice, err)
+
+	_, err = NewArray(String16{}, []string{}, 0)
+	ta.Equal(ErrInvalidInterval, errors.Cause(err))
+
+	a, err := NewArray(String16{}, []string{"abc", "d"})
+	ta.NoError(err)
+	b := a.Marshal()
+
+	for i := 0; i < len(b); i++ {
+		_, _, err = UnmarshalArray(String16{}, b[:i])
+		ta.Equal(ErrShortBuffer, errors.Cause(err), "len: %d", i)
+	}
+
+	// unmarshal with a wrong codec
+
+	_, _, err = UnmarshalArray(U8{}, b)
+	ta.Equal(ErrMalformed, errors.Cause(err))
+
+	// broken index: offsets of "abc", "d", "ef" are 0, 5, 8 and data size is 12
+
+	for _, c := range []struct{ i, off uint32 }{
+		{0, 1},
+		{2, 3},
+		{2, 13},
+		{1, 255},
+	} {
+		a, err = NewArray(String16{}, []string{"abc", "d", "ef"}, 1)
+		ta.NoError(err)
+		b = a.Marshal()
+		binary.LittleEndian.PutUint32(b[arrayHeaderSize+4*c.i:], c.off)
+
+		_, _, err = UnmarshalArray(String16{}, b)
+		ta.Equal(ErrMalformed, errors.Cause(err), "index: %d, offset: %d", c.i, c.off)
+	}
+
+	// zero-size elements of a var-length Codec: offsets equal data size
+	a, err = NewArray(noStreamSizer{Dummy{}}, []int{1, 2, 3}, 1)
+	ta.NoError(err)
+	b = a.Marshal()
+
+	ua, n, err := UnmarshalArray(noStreamSizer{Dummy{}}, b)
+	ta.NoError(err)
+	ta.Equal(len(b), n)
+	ta.Equal(3, ua.Len())
+	ta.Nil(ua.Get(2))
+
+	a, err = NewArray(U16{}, []uint16{1, 2})
+	ta.NoError(err)
+	b = a.Marshal()
+
+	_, _, err = UnmarshalArray(U32{}, b)
+	ta.Equal(ErrMalformed, errors.Cause(err))
+
+	_, _, err = UnmarshalArray(String16{}, b)
+	ta.Equal(ErrInvalidInterval, errors.Cause(err))
+}
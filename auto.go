@@ -0,0 +1,414 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// AutoEncoding is an encoding Auto chooses for an integer array.
+type AutoEncoding uint8
+
+const (
+	// AutoFixed stores every integer in its own size, little-endian.
+	AutoFixed AutoEncoding = iota
+	// AutoVarint stores every integer in a varint, zigzag for signed.
+	AutoVarint
+	// AutoDelta stores the zigzag varint of differences from the previous
+	// integer.
+	AutoDelta
+	// AutoFOR stores integers with FrameOfRef.
+	AutoFOR
+	// AutoRLE stores integers with RLECodec.
+	AutoRLE
+	// AutoBlocked indicates every block has its own encoding.
+	AutoBlocked AutoEncoding = 0xff
+)
+
+var autoEncodingNames = map[AutoEncoding]string{
+	AutoFixed:   "fixed",
+	AutoVarint:  "varint",
+	AutoDelta:   "delta",
+	AutoFOR:     "for",
+	AutoRLE:     "rle",
+	AutoBlocked: "blocked",
+}
+
+// autoCandidates are the encodings Auto chooses from, in order of preference
+// for equal sizes.
+var autoCandidates = []AutoEncoding{AutoFixed, AutoFOR, AutoRLE, AutoVarint, AutoDelta}
+
+// String returns the name of the encoding.
+func (e AutoEncoding) String() string {
+	if n, ok := autoEncodingNames[e]; ok {
+		return n
+	}
+	return "unknown"
+}
+
+// AutoEstimate is the encoded size of an integer array with an encoding.
+type AutoEstimate struct {
+	Encoding AutoEncoding
+	Size     int
+}
+
+// Auto is a Codec of integer slices that tries fixed width, varint, delta,
+// frame-of-reference and run-length encoding and chooses the smallest.
+// It accepts any slice GetSliceEltCodec accepts, and decodes to the same type.
+//
+// If BlockSize is positive, the slice is split into blocks of BlockSize
+// integers and the encoding is chosen for every block.
+//
+// The encoded layout is:
+//
+//     kind     uint8 // reflect.Kind of element
+//     choice   uint8 // AutoEncoding
+//     payload:
+//       fixed:   uint32 n, n * element size
+//       varint:  uint32 n, uint32 size, varints
+//       delta:   uint32 n, uint32 size, varints
+//       for:     FrameOfRef
+//       rle:     RLECodec of the element Codec
+//       blocked: uint32 n, uint32 block size, uint32 * (nblocks+1) offsets,
+//                then every block encoded by Auto without BlockSize
+//
+// Integers in headers are little-endian.
+type Auto struct {
+	// BlockSize is the number of integers in a block to choose the encoding
+	// for. 0 means the whole slice is a block.
+	BlockSize int
+}
+
+// Encode converts a slice of integers to bytes with the smallest encoding.
+// It panics if d is not a slice of integer of fixed size.
+func (c Auto) Encode(d interface{}) []byte {
+	if c.BlockSize < 0 {
+		panic(errors.Wrapf(ErrInvalidInterval, "block size: %d", c.BlockSize))
+	}
+
+	vals, kind, err := toOrderedUint64s(d)
+	if err != nil {
+		panic(err)
+	}
+
+	if c.BlockSize == 0 || len(vals) <= c.BlockSize {
+		return autoEncode(d, vals, kind, c.choose(d, vals, kind))
+	}
+
+	sl := reflect.ValueOf(d)
+
+	var offsets, blocks []byte
+	for i := 0; i < len(vals); i += c.BlockSize {
+		end := i + c.BlockSize
+		if end > len(vals) {
+			end = len(vals)
+		}
+		offsets = appendU32(offsets, len(blocks))
+		blocks = append(blocks, Auto{}.Encode(sl.Slice(i, end).Interface())...)
+	}
+	offsets = appendU32(offsets, len(blocks))
+
+	rst := []byte{byte(kind), byte(AutoBlocked)}
+	rst = appendU32(rst, len(vals))
+	rst = appendU32(rst, c.BlockSize)
+	rst = append(rst, offsets...)
+	return append(rst, blocks...)
+}
+
+// Decode converts bytes to a slice of integers.
+// It returns number bytes consumed and the slice.
+func (c Auto) Decode(b []byte) (int, interface{}) {
+	kind := reflect.Kind(b[0])
+	p := b[2:]
+
+	switch AutoEncoding(b[1]) {
+	case AutoFixed:
+		n := int(binary.LittleEndian.Uint32(p))
+		size := autoEltSize(kind)
+		vals := make([]uint64, n)
+		for i := range vals {
+			vals[i] = orderedFromRaw(kind, leUint(p[4+i*size:4+(i+1)*size]))
+		}
+		return 2 + 4 + n*size, fromOrderedUint64s(kind, vals)
+
+	case AutoVarint, AutoDelta:
+		n := int(binary.LittleEndian.Uint32(p))
+		size := int(binary.LittleEndian.Uint32(p[4:]))
+		data := p[8 : 8+size]
+
+		vals := make([]uint64, n)
+		var prev uint64
+		off := 0
+		for i := range vals {
+			var u uint64
+			var k int
+			if AutoEncoding(b[1]) == AutoDelta || isSignedKind(kind) {
+				var x int64
+				x, k = binary.Varint(data[off:])
+				u = uint64(x)
+			} else {
+				u, k = binary.Uvarint(data[off:])
+			}
+			if k <= 0 {
+				panic(errors.Wrapf(ErrMalformed, "invalid varint at: %d", off))
+			}
+			off += k
+
+			if AutoEncoding(b[1]) == AutoDelta {
+				prev += u
+				vals[i] = prev
+			} else {
+				vals[i] = orderedFromRaw(kind, u)
+			}
+		}
+		return 2 + 8 + size, fromOrderedUint64s(kind, vals)
+
+	case AutoFOR:
+		n, v := FrameOfRef{}.Decode(p)
+		return 2 + n, v
+
+	case AutoRLE:
+		r := autoRLE(kind)
+		n, v := r.Decode(p)
+		return 2 + n, v
+
+	case AutoBlocked:
+		n := int(binary.LittleEndian.Uint32(p))
+		start, nblocks := autoBlocksStart(p)
+
+		typ, err := intKindType(kind)
+		if err != nil {
+			panic(err)
+		}
+		rst := reflect.MakeSlice(reflect.SliceOf(typ), 0, n)
+		for blk := 0; blk < nblocks; blk++ {
+			off := int(binary.LittleEndian.Uint32(p[8+4*blk:]))
+			_, v := Auto{}.Decode(p[start+off:])
+			rst = reflect.AppendSlice(rst, reflect.ValueOf(v))
+		}
+		end := int(binary.LittleEndian.Uint32(p[8+4*nblocks:]))
+		return 2 + start + end, rst.Interface()
+	}
+
+	panic(errors.Wrapf(ErrMalformed, "auto encoding: %d", b[1]))
+}
+
+// Size returns the size in byte after encoding v.
+func (c Auto) Size(d interface{}) int {
+	return len(c.Encode(d))
+}
+
+// EncodedSize returns size of the encoded slice, from headers without
+// decoding.
+func (c Auto) EncodedSize(b []byte) int {
+	kind := reflect.Kind(b[0])
+	p := b[2:]
+
+	switch AutoEncoding(b[1]) {
+	case AutoFixed:
+		n := int(binary.LittleEndian.Uint32(p))
+		return 2 + 4 + n*autoEltSize(kind)
+
+	case AutoVarint, AutoDelta:
+		return 2 + 8 + int(binary.LittleEndian.Uint32(p[4:]))
+
+	case AutoFOR:
+		return 2 + FrameOfRef{}.EncodedSize(p)
+
+	case AutoRLE:
+		return 2 + autoRLE(kind).EncodedSize(p)
+
+	case AutoBlocked:
+		start, nblocks := autoBlocksStart(p)
+		end := int(binary.LittleEndian.Uint32(p[8+4*nblocks:]))
+		return 2 + start + end
+	}
+
+	panic(errors.Wrapf(ErrMalformed, "auto encoding: %d", b[1]))
+}
+
+// NeededSize returns the encoded size if the headers of the chosen encoding
+// are complete.
+func (c Auto) NeededSize(b []byte) int {
+	if len(b) < 2 {
+		return 2
+	}
+
+	kind := reflect.Kind(b[0])
+	p := b[2:]
+
+	switch AutoEncoding(b[1]) {
+	case AutoFixed:
+		if len(p) < 4 {
+			return 2 + 4
+		}
+	case AutoVarint, AutoDelta:
+		if len(p) < 8 {
+			return 2 + 8
+		}
+	case AutoFOR:
+		return 2 + FrameOfRef{}.NeededSize(p)
+	case AutoRLE:
+		return 2 + autoRLE(kind).NeededSize(p)
+	case AutoBlocked:
+		if len(p) < 8 {
+			return 2 + 8
+		}
+		start, _ := autoBlocksStart(p)
+		if len(p) < start {
+			return 2 + start
+		}
+	}
+	return c.EncodedSize(b)
+}
+
+// Choice returns the encoding of an encoded slice.
+// It is AutoBlocked if every block is encoded separately.
+func (c Auto) Choice(b []byte) AutoEncoding {
+	return AutoEncoding(b[1])
+}
+
+// BlockChoices returns the encoding of every block of an encoded slice, or a
+// single encoding if it is not encoded in blocks.
+func (c Auto) BlockChoices(b []byte) []AutoEncoding {
+	if AutoEncoding(b[1]) != AutoBlocked {
+		return []AutoEncoding{AutoEncoding(b[1])}
+	}
+
+	p := b[2:]
+	start, nblocks := autoBlocksStart(p)
+
+	rst := make([]AutoEncoding, nblocks)
+	for blk := range rst {
+		off := int(binary.LittleEndian.Uint32(p[8+4*blk:]))
+		rst[blk] = AutoEncoding(p[start+off+1])
+	}
+	return rst
+}
+
+// Estimates returns the encoded size of d, without BlockSize, with every
+// encoding, smallest first.
+// It panics if d is not a slice of integer of fixed size.
+func (c Auto) Estimates(d interface{}) []AutoEstimate {
+	vals, kind, err := toOrderedUint64s(d)
+	if err != nil {
+		panic(err)
+	}
+	return autoEstimates(d, vals, kind)
+}
+
+func (c Auto) choose(d interface{}, vals []uint64, kind reflect.Kind) AutoEncoding {
+	return autoEstimates(d, vals, kind)[0].Encoding
+}
+
+func autoEstimates(d interface{}, vals []uint64, kind reflect.Kind) []AutoEstimate {
+	rst := make([]AutoEstimate, len(autoCandidates))
+	for i, e := range autoCandidates {
+		rst[i] = AutoEstimate{
+			Encoding: e,
+			Size:     len(autoEncode(d, vals, kind, e)),
+		}
+	}
+	sort.SliceStable(rst, func(i, j int) bool { return rst[i].Size < rst[j].Size })
+	return rst
+}
+
+// autoEncode encodes with encoding e.
+func autoEncode(d interface{}, vals []uint64, kind reflect.Kind, e AutoEncoding) []byte {
+	rst := []byte{byte(kind), byte(e)}
+
+	switch e {
+	case AutoFixed:
+		size := autoEltSize(kind)
+		rst = appendU32(rst, len(vals))
+		var buf [8]byte
+		for _, u := range vals {
+			binary.LittleEndian.PutUint64(buf[:], rawFromOrdered(kind, u))
+			rst = append(rst, buf[:size]...)
+		}
+		return rst
+
+	case AutoVarint, AutoDelta:
+		var data []byte
+		var buf [binary.MaxVarintLen64]byte
+		var prev uint64
+		for _, u := range vals {
+			var n int
+			switch {
+			case e == AutoDelta:
+				n = binary.PutVarint(buf[:], int64(u-prev))
+				prev = u
+			case isSignedKind(kind):
+				n = binary.PutVarint(buf[:], int64(rawFromOrdered(kind, u)))
+			default:
+				n = binary.PutUvarint(buf[:], u)
+			}
+			data = append(data, buf[:n]...)
+		}
+		rst = appendU32(rst, len(vals))
+		rst = appendU32(rst, len(data))
+		return append(rst, data...)
+
+	case AutoFOR:
+		return append(rst, FrameOfRef{}.Encode(d)...)
+
+	case AutoRLE:
+		return append(rst, autoRLE(kind).Encode(d)...)
+	}
+
+	panic(errors.Errorf("unknown auto encoding: %d", e))
+}
+
+// autoBlocksStart returns the offset of the first block in a blocked payload
+// and the number of blocks.
+func autoBlocksStart(p []byte) (int, int) {
+	n := int(binary.LittleEndian.Uint32(p))
+	bs := int(binary.LittleEndian.Uint32(p[4:]))
+	if bs <= 0 {
+		panic(errors.Wrapf(ErrMalformed, "block size: %d", bs))
+	}
+	nblocks := (n + bs - 1) / bs
+	return 8 + 4*(nblocks+1), nblocks
+}
+
+func autoRLE(kind reflect.Kind) *RLECodec {
+	elt, err := CodecByKind(kind)
+	if err != nil {
+		panic(err)
+	}
+	r, err := RLE(elt)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func autoEltSize(kind reflect.Kind) int {
+	elt, err := CodecByKind(kind)
+	if err != nil {
+		panic(errors.Wrapf(err, "kind: %v", kind))
+	}
+	size, _ := fixedSize(elt)
+	return size
+}
+
+// rawFromOrdered converts an ordered uint64 to the two's complement bits of
+// the integer.
+func rawFromOrdered(kind reflect.Kind, u uint64) uint64 {
+	if isSignedKind(kind) {
+		return u ^ signBit
+	}
+	return u
+}
+
+// orderedFromRaw converts the little-endian bits of an integer of kind, which
+// may be narrower than 64 bits, to an ordered uint64.
+func orderedFromRaw(kind reflect.Kind, raw uint64) uint64 {
+	if !isSignedKind(kind) {
+		return raw
+	}
+	shift := uint(64 - 8*autoEltSize(kind))
+	return uint64(int64(raw<<shift)>>shift) ^ signBit
+}
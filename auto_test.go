@@ -0,0 +1,177 @@
+package qcodec
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuto(t *testing.T) {
+
+	ta := require.New(t)
+
+	rnd := rand.New(rand.NewSource(1))
+
+	random := make([]uint64, 500)
+	for i := range random {
+		random[i] = rnd.Uint64()
+	}
+
+	sorted := make([]uint32, 1000)
+	for i := range sorted {
+		sorted[i] = 1<<31 + uint32(i*3)
+	}
+
+	small := make([]int64, 1000)
+	for i := range small {
+		small[i] = int64(rnd.Intn(100)) - 50
+		// outliers make frame-of-reference wide
+		if i%50 == 0 {
+			small[i] = 1 << 40
+		}
+	}
+
+	narrow := make([]uint32, 1000)
+	for i := range narrow {
+		narrow[i] = 1<<30 + uint32(rnd.Intn(200))
+	}
+
+	runs := make([]int16, 1000)
+	for i := range runs {
+		runs[i] = int16(i / 300)
+	}
+
+	cases := []struct {
+		input interface{}
+		want  AutoEncoding
+	}{
+		{[]uint8{}, AutoFixed},
+		{random, AutoFixed},
+		{sorted, AutoDelta},
+		{small, AutoVarint},
+		{narrow, AutoFOR},
+		{runs, AutoRLE},
+		{[]int8{-128, 127, 0, -1}, AutoFixed},
+		{[]int32{math.MinInt32, math.MaxInt32}, AutoFixed},
+	}
+
+	c := Auto{}
+
+	for i, tc := range cases {
+		b := c.Encode(tc.input)
+		ta.Equal(len(b), c.Size(tc.input), "%d-th", i+1)
+		ta.Equal(len(b), c.EncodedSize(b))
+		ta.Equal(tc.want, c.Choice(b), "%d-th: %v", i+1, c.Estimates(tc.input))
+		ta.Equal([]AutoEncoding{tc.want}, c.BlockChoices(b))
+
+		n, v := c.Decode(append(b, 1))
+		ta.Equal(len(b), n)
+		ta.Equal(tc.input, v)
+
+		// every encoding round-trips
+		est := c.Estimates(tc.input)
+		ta.Equal(len(autoCandidates), len(est))
+		ta.Equal(tc.want, est[0].Encoding)
+		ta.Equal(len(b), est[0].Size)
+
+		vals, kind, err := toOrderedUint64s(tc.input)
+		ta.NoError(err)
+		for k, e := range est {
+			if k > 0 {
+				ta.True(est[k-1].Size <= e.Size)
+			}
+			eb := autoEncode(tc.input, vals, kind, e.Encoding)
+			ta.Equal(e.Size, len(eb))
+
+			n, v := c.Decode(eb)
+			ta.Equal(len(eb), n)
+			ta.Equal(tc.input, v, "%d-th: encoding: %v", i+1, e.Encoding)
+			ta.Equal(len(eb), c.EncodedSize(eb))
+			checkAutoNeededSize(ta, eb)
+		}
+	}
+}
+
+func TestAutoBlocks(t *testing.T) {
+
+	ta := require.New(t)
+
+	// runs, then sorted, then random
+	vals := make([]uint64, 0, 300)
+	for i := 0; i < 100; i++ {
+		vals = append(vals, 7)
+	}
+	for i := 0; i < 100; i++ {
+		vals = append(vals, uint64(1<<40+i*5))
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		vals = append(vals, rnd.Uint64())
+	}
+
+	c := Auto{BlockSize: 100}
+	b := c.Encode(vals)
+	ta.Equal(AutoBlocked, c.Choice(b))
+	ta.Equal([]AutoEncoding{AutoRLE, AutoDelta, AutoFixed}, c.BlockChoices(b))
+	ta.True(len(b) < len(Auto{}.Encode(vals)))
+
+	ta.Equal(len(b), c.Size(vals))
+	ta.Equal(len(b), c.EncodedSize(b))
+	checkAutoNeededSize(ta, b)
+
+	n, v := c.Decode(append(b, 1))
+	ta.Equal(len(b), n)
+	ta.Equal(vals, v)
+
+	// last block shorter
+	b = Auto{BlockSize: 128}.Encode(vals)
+	ta.Equal(3, len(c.BlockChoices(b)))
+	_, v = c.Decode(b)
+	ta.Equal(vals, v)
+
+	// a single block is not blocked
+	b = Auto{BlockSize: 1000}.Encode(vals)
+	ta.NotEqual(AutoBlocked, c.Choice(b))
+
+	testPanic(t, func() { Auto{BlockSize: -1}.Encode(vals) }, "negative block size")
+	testPanic(t, func() { c.Encode([]int{1}) }, "int is not fixed size")
+	testPanic(t, func() { c.Decode([]byte{byte(8), 100}) }, "unknown encoding")
+
+	ta.Equal("delta", AutoDelta.String())
+	ta.Equal("blocked", AutoBlocked.String())
+	ta.Equal("unknown", AutoEncoding(100).String())
+}
+
+func TestAutoDecoder(t *testing.T) {
+
+	ta := require.New(t)
+
+	input := []uint32{5, 1000, 7, 1 << 20}
+	b := Auto{}.Encode(input)
+
+	// fed one byte at a time, the size is known once the header is complete
+	d := NewDecoder(Auto{})
+	for i := 0; i < len(b)-1; i++ {
+		vals, need, err := d.Feed(b[i : i+1])
+		ta.NoError(err)
+		ta.Empty(vals)
+		if i >= 2+8 {
+			ta.Equal(len(b)-i-1, need, "fed: %d", i+1)
+		}
+	}
+	vals, _, err := d.Feed(b[len(b)-1:])
+	ta.NoError(err)
+	ta.Equal([]interface{}{input}, vals)
+}
+
+// checkAutoNeededSize checks NeededSize of every prefix of an encoded slice.
+func checkAutoNeededSize(ta *require.Assertions, b []byte) {
+	c := Auto{}
+	for i := 0; i < len(b); i++ {
+		ta.True(c.NeededSize(b[:i]) > i, "prefix: %d", i)
+		ta.True(c.NeededSize(b[:i]) <= len(b), "prefix: %d", i)
+	}
+	ta.Equal(len(b), c.NeededSize(b))
+}
@@ -0,0 +1,336 @@
+package qcodec
+
+import (
+	"bytes"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrInvalidWidth indicates a bit width is not in [1, 64].
+	ErrInvalidWidth = errors.New("bit width must be in [1, 64]")
+	// ErrOutOfRange indicates a value does not fit in the declared bit width.
+	ErrOutOfRange = errors.New("value out of range")
+)
+
+// A BitCodec encodes a value into a fixed number of bits, so that several of
+// them can be packed together without byte alignment, such as by BitTuple.
+type BitCodec interface {
+	// BitWidth returns the number of bits of every encoded value.
+	BitWidth() int
+	// EncodeBits writes v to w.
+	// It returns an error if v can not be encoded, and nothing is written.
+	EncodeBits(w *BitWriter, v interface{}) error
+	// DecodeBits reads a value from r.
+	DecodeBits(r *BitReader) (interface{}, error)
+}
+
+// UintNCodec is a Codec and BitCodec of unsigned integers of "n" bits.
+// It accepts an integer of any type in [0, 2^n) and decodes to uint64.
+//
+// As a Codec a value takes ceil(n/8) bytes, big-endian and padded with zero
+// bits at the end, thus the encoded values are in the same order as integers.
+type UintNCodec struct {
+	bits uint
+}
+
+// UintN creates a *UintNCodec of "bits" bits, in [1, 64].
+func UintN(bits int) (*UintNCodec, error) {
+	if bits < 1 || bits > 64 {
+		return nil, errors.Wrapf(ErrInvalidWidth, "bits: %d", bits)
+	}
+	return &UintNCodec{bits: uint(bits)}, nil
+}
+
+// BitWidth returns the number of bits.
+func (c *UintNCodec) BitWidth() int {
+	return int(c.bits)
+}
+
+// EncodeBits writes v in n bits to w.
+// It returns an error wrapping ErrOutOfRange if v is negative or not less than
+// 2^n, or ErrUnknownEltType if v is not an integer.
+func (c *UintNCodec) EncodeBits(w *BitWriter, v interface{}) error {
+	rv, err := bitIntValue(v)
+	if err != nil {
+		return err
+	}
+
+	var u uint64
+	if isSignedKind(rv.Kind()) {
+		x := rv.Int()
+		if x < 0 {
+			return errors.Wrapf(ErrOutOfRange, "%d for uint%d", x, c.bits)
+		}
+		u = uint64(x)
+	} else {
+		u = rv.Uint()
+	}
+
+	if c.bits < 64 && u>>c.bits != 0 {
+		return errors.Wrapf(ErrOutOfRange, "%d for uint%d", u, c.bits)
+	}
+
+	w.WriteBits(u, c.bits)
+	return nil
+}
+
+// DecodeBits reads n bits from r and returns an uint64.
+func (c *UintNCodec) DecodeBits(r *BitReader) (interface{}, error) {
+	return r.ReadBits(c.bits)
+}
+
+// Encode converts an integer to ceil(n/8) bytes.
+// It panics if v is out of range.
+func (c *UintNCodec) Encode(v interface{}) []byte {
+	return encodeBitCodec(c, v)
+}
+
+// Decode converts bytes to an uint64.
+// It returns number bytes consumed and the value.
+func (c *UintNCodec) Decode(b []byte) (int, interface{}) {
+	return decodeBitCodec(c, b)
+}
+
+// Size returns ceil(n/8).
+func (c *UintNCodec) Size(v interface{}) int {
+	return c.EncodedSize(nil)
+}
+
+// EncodedSize returns ceil(n/8).
+func (c *UintNCodec) EncodedSize(b []byte) int {
+	return int(c.bits+7) / 8
+}
+
+// Compare compares two encoded values as integers.
+func (c *UintNCodec) Compare(a, b []byte) int {
+	n := c.EncodedSize(nil)
+	return bytes.Compare(a[:n], b[:n])
+}
+
+// IntNCodec is a Codec and BitCodec of signed integers of "n" bits, in two's
+// complement.
+// It accepts an integer of any type in [-2^(n-1), 2^(n-1)) and decodes to
+// int64.
+//
+// As a Codec a value takes ceil(n/8) bytes, big-endian and padded with zero
+// bits at the end.
+type IntNCodec struct {
+	bits uint
+}
+
+// IntN creates a *IntNCodec of "bits" bits, in [1, 64].
+func IntN(bits int) (*IntNCodec, error) {
+	if bits < 1 || bits > 64 {
+		return nil, errors.Wrapf(ErrInvalidWidth, "bits: %d", bits)
+	}
+	return &IntNCodec{bits: uint(bits)}, nil
+}
+
+// BitWidth returns the number of bits.
+func (c *IntNCodec) BitWidth() int {
+	return int(c.bits)
+}
+
+// EncodeBits writes v in n bits to w.
+// It returns an error wrapping ErrOutOfRange if v is out of
+// [-2^(n-1), 2^(n-1)), or ErrUnknownEltType if v is not an integer.
+func (c *IntNCodec) EncodeBits(w *BitWriter, v interface{}) error {
+	rv, err := bitIntValue(v)
+	if err != nil {
+		return err
+	}
+
+	var x int64
+	if isSignedKind(rv.Kind()) {
+		x = rv.Int()
+	} else {
+		u := rv.Uint()
+		if u>>63 != 0 {
+			return errors.Wrapf(ErrOutOfRange, "%d for int%d", u, c.bits)
+		}
+		x = int64(u)
+	}
+
+	min, max := -int64(1)<<(c.bits-1), int64(uint64(1)<<(c.bits-1)-1)
+	if x < min || x > max {
+		return errors.Wrapf(ErrOutOfRange, "%d for int%d", x, c.bits)
+	}
+
+	w.WriteBits(uint64(x), c.bits)
+	return nil
+}
+
+// DecodeBits reads n bits from r and returns an int64.
+func (c *IntNCodec) DecodeBits(r *BitReader) (interface{}, error) {
+	u, err := r.ReadBits(c.bits)
+	if err != nil {
+		return nil, err
+	}
+	shift := 64 - c.bits
+	return int64(u<<shift) >> shift, nil
+}
+
+// Encode converts an integer to ceil(n/8) bytes.
+// It panics if v is out of range.
+func (c *IntNCodec) Encode(v interface{}) []byte {
+	return encodeBitCodec(c, v)
+}
+
+// Decode converts bytes to an int64.
+// It returns number bytes consumed and the value.
+func (c *IntNCodec) Decode(b []byte) (int, interface{}) {
+	return decodeBitCodec(c, b)
+}
+
+// Size returns ceil(n/8).
+func (c *IntNCodec) Size(v interface{}) int {
+	return c.EncodedSize(nil)
+}
+
+// EncodedSize returns ceil(n/8).
+func (c *IntNCodec) EncodedSize(b []byte) int {
+	return int(c.bits+7) / 8
+}
+
+// Compare decodes two values and compares them as integers.
+func (c *IntNCodec) Compare(a, b []byte) int {
+	_, x := c.Decode(a)
+	_, y := c.Decode(b)
+	return cmpInt64(x.(int64), y.(int64))
+}
+
+// BitTupleCodec packs a fixed list of BitCodec fields into bits, such as a
+// 3-bit type and a 13-bit length in 2 bytes.
+// It encodes a []interface{} with one value for each field and decodes to the
+// same.
+// A BitTupleCodec is also a BitCodec, thus it can be nested.
+//
+// As a Codec a tuple takes ceil(total bits/8) bytes, fields are written in
+// order, most significant bit first, and padded with zero bits at the end.
+type BitTupleCodec struct {
+	fields []BitCodec
+	bits   int
+}
+
+// BitTuple creates a *BitTupleCodec of "fields".
+func BitTuple(fields ...BitCodec) (*BitTupleCodec, error) {
+	if len(fields) == 0 {
+		return nil, errors.Errorf("bit tuple without field")
+	}
+
+	c := &BitTupleCodec{fields: fields}
+	for _, f := range fields {
+		c.bits += f.BitWidth()
+	}
+	return c, nil
+}
+
+// BitWidth returns the total number of bits of all fields.
+func (c *BitTupleCodec) BitWidth() int {
+	return c.bits
+}
+
+// EncodeBits writes every field of v, a []interface{}, to w.
+// It returns an error if the number of values does not match or any value can
+// not be encoded, and nothing is written.
+func (c *BitTupleCodec) EncodeBits(w *BitWriter, v interface{}) error {
+	vals, ok := v.([]interface{})
+	if !ok {
+		return errors.Wrapf(ErrUnknownEltType, "type: %T, want: []interface{}", v)
+	}
+	if len(vals) != len(c.fields) {
+		return errors.Errorf("number of values: %d, fields: %d", len(vals), len(c.fields))
+	}
+
+	// encode to a temporary writer so that nothing is written on error.
+	tmp := &BitWriter{}
+	for i, f := range c.fields {
+		if err := f.EncodeBits(tmp, vals[i]); err != nil {
+			return errors.WithMessagef(err, "field: %d", i)
+		}
+	}
+
+	r := NewBitReader(tmp.Bytes())
+	for left := c.bits; left > 0; left -= 64 {
+		n := left
+		if n > 64 {
+			n = 64
+		}
+		u, _ := r.ReadBits(uint(n))
+		w.WriteBits(u, uint(n))
+	}
+	return nil
+}
+
+// DecodeBits reads every field from r and returns a []interface{}.
+func (c *BitTupleCodec) DecodeBits(r *BitReader) (interface{}, error) {
+	vals := make([]interface{}, len(c.fields))
+	for i, f := range c.fields {
+		v, err := f.DecodeBits(r)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+// Encode converts a []interface{} to bytes.
+// It panics if v can not be encoded.
+func (c *BitTupleCodec) Encode(v interface{}) []byte {
+	return encodeBitCodec(c, v)
+}
+
+// Decode converts bytes to a []interface{}.
+// It returns number bytes consumed and the values.
+func (c *BitTupleCodec) Decode(b []byte) (int, interface{}) {
+	return decodeBitCodec(c, b)
+}
+
+// Size returns ceil(total bits/8).
+func (c *BitTupleCodec) Size(v interface{}) int {
+	return c.EncodedSize(nil)
+}
+
+// EncodedSize returns ceil(total bits/8).
+func (c *BitTupleCodec) EncodedSize(b []byte) int {
+	return (c.bits + 7) / 8
+}
+
+func encodeBitCodec(c BitCodec, v interface{}) []byte {
+	w := &BitWriter{}
+	if err := c.EncodeBits(w, v); err != nil {
+		panic(err)
+	}
+	return w.Bytes()
+}
+
+func decodeBitCodec(c BitCodec, b []byte) (int, interface{}) {
+	n := (c.BitWidth() + 7) / 8
+	v, err := c.DecodeBits(NewBitReader(b[:n]))
+	if err != nil {
+		panic(err)
+	}
+	return n, v
+}
+
+// bitIntValue returns the reflect.Value of v if it is an integer.
+func bitIntValue(v interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv, nil
+	}
+	return rv, errors.Wrapf(ErrUnknownEltType, "type: %T, want: integer", v)
+}
+
+func isSignedKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+package qcodec
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUintN(t *testing.T) {
+
+	ta := require.New(t)
+
+	cases := []struct {
+		bits  int
+		input interface{}
+		want  []byte
+	}{
+		{1, uint8(1), []byte{0x80}},
+		{3, 5, []byte{0xa0}},
+		{8, uint16(255), []byte{0xff}},
+		{13, uint32(0x1234), []byte{0x91, 0xa0}},
+		{16, int64(0x1234), []byte{0x12, 0x34}},
+		{64, uint64(math.MaxUint64), []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	}
+
+	for i, c := range cases {
+		m, err := UintN(c.bits)
+		ta.NoError(err)
+		ta.Equal(c.bits, m.BitWidth())
+
+		b := m.Encode(c.input)
+		ta.Equal(c.want, b, "%d-th: case: %+v", i+1, c)
+		ta.Equal(len(b), m.Size(c.input))
+		ta.Equal(len(b), m.EncodedSize(b))
+
+		n, v := m.Decode(append(b, 1))
+		ta.Equal(len(b), n)
+		ta.Equal(reflectUint64(c.input), v)
+	}
+}
+
+func TestIntN(t *testing.T) {
+
+	ta := require.New(t)
+
+	cases := []struct {
+		bits  int
+		input interface{}
+		want  []byte
+	}{
+		{1, -1, []byte{0x80}},
+		{1, 0, []byte{0x00}},
+		{3, -4, []byte{0x80}},
+		{3, int8(3), []byte{0x60}},
+		{12, -2, []byte{0xff, 0xe0}},
+		{64, int64(math.MinInt64), []byte{0x80, 0, 0, 0, 0, 0, 0, 0}},
+		{64, uint64(math.MaxInt64), []byte{0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	}
+
+	for i, c := range cases {
+		m, err := IntN(c.bits)
+		ta.NoError(err)
+
+		b := m.Encode(c.input)
+		ta.Equal(c.want, b, "%d-th: case: %+v", i+1, c)
+		ta.Equal(len(b), m.Size(c.input))
+		ta.Equal(len(b), m.EncodedSize(b))
+
+		n, v := m.Decode(b)
+		ta.Equal(len(b), n)
+		ta.Equal(reflectInt64(c.input), v)
+	}
+}
+
+func TestBitIntRange(t *testing.T) {
+
+	ta := require.New(t)
+
+	_, err := UintN(0)
+	ta.Equal(ErrInvalidWidth, errors.Cause(err))
+	_, err = IntN(65)
+	ta.Equal(ErrInvalidWidth, errors.Cause(err))
+
+	u3, _ := UintN(3)
+	i3, _ := IntN(3)
+	i64, _ := IntN(64)
+
+	cases := []struct {
+		c     BitCodec
+		input interface{}
+		want  error
+	}{
+		{u3, 7, nil},
+		{u3, 8, ErrOutOfRange},
+		{u3, -1, ErrOutOfRange},
+		{u3, "1", ErrUnknownEltType},
+		{i3, 3, nil},
+		{i3, -4, nil},
+		{i3, 4, ErrOutOfRange},
+		{i3, -5, ErrOutOfRange},
+		{i3, uint(4), ErrOutOfRange},
+		{i64, uint64(math.MaxInt64 + 1), ErrOutOfRange},
+		{i64, 1.5, ErrUnknownEltType},
+	}
+
+	for i, c := range cases {
+		w := &BitWriter{}
+		err := c.c.EncodeBits(w, c.input)
+		ta.Equal(c.want, errors.Cause(err), "%d-th: case: %+v", i+1, c)
+		if err != nil {
+			ta.Equal(0, w.Len())
+			testPanic(t, func() { c.c.(Codec).Encode(c.input) }, "out of range")
+		}
+	}
+}
+
+func TestBitIntCompare(t *testing.T) {
+
+	ta := require.New(t)
+
+	u5, _ := UintN(5)
+	i5, _ := IntN(5)
+
+	cases := []struct {
+		c    Codec
+		ints []int
+	}{
+		{u5, []int{31, 0, 7, 16, 1}},
+		{i5, []int{15, -16, 0, -1, 7}},
+	}
+
+	for _, cc := range cases {
+		c, ints := cc.c, cc.ints
+
+		var encoded [][]byte
+		for _, x := range ints {
+			encoded = append(encoded, c.Encode(x))
+		}
+
+		sort.Ints(ints)
+		SortRecords(encoded, c)
+
+		for i, x := range ints {
+			_, v := c.Decode(encoded[i])
+			ta.EqualValues(x, v)
+		}
+	}
+}
+
+func TestBitTuple(t *testing.T) {
+
+	ta := require.New(t)
+
+	typ, _ := UintN(3)
+	length, _ := UintN(13)
+	delta, _ := IntN(4)
+
+	hdr, err := BitTuple(typ, length)
+	ta.NoError(err)
+	ta.Equal(16, hdr.BitWidth())
+
+	b := hdr.Encode([]interface{}{5, 0x1abc})
+	ta.Equal([]byte{0xba, 0xbc}, b)
+
+	n, v := hdr.Decode(b)
+	ta.Equal(2, n)
+	ta.Equal([]interface{}{uint64(5), uint64(0x1abc)}, v)
+
+	// nested: 16 + 4 bits
+	outer, err := BitTuple(hdr, delta)
+	ta.NoError(err)
+	ta.Equal(20, outer.BitWidth())
+	ta.Equal(3, outer.Size(nil))
+
+	b = outer.Encode([]interface{}{[]interface{}{1, 2}, -1})
+	ta.Equal([]byte{0x20, 0x02, 0xf0}, b)
+
+	_, v = outer.Decode(b)
+	ta.Equal([]interface{}{[]interface{}{uint64(1), uint64(2)}, int64(-1)}, v)
+
+	// in a bit stream with other fields
+	w := &BitWriter{}
+	w.WriteBit(true)
+	ta.NoError(hdr.EncodeBits(w, []interface{}{7, 1}))
+	ta.Equal(17, w.Len())
+
+	r := NewBitReader(w.Bytes())
+	bit, _ := r.ReadBit()
+	ta.True(bit)
+	v, err = hdr.DecodeBits(r)
+	ta.NoError(err)
+	ta.Equal([]interface{}{uint64(7), uint64(1)}, v)
+	ta.Equal(17, r.Offset())
+	ta.Equal(7, r.Remaining())
+
+	_, err = hdr.DecodeBits(r)
+	ta.Equal(ErrShortBuffer, errors.Cause(err))
+
+	// errors write nothing
+	w = &BitWriter{}
+	err = hdr.EncodeBits(w, []interface{}{8, 1})
+	ta.Equal(ErrOutOfRange, errors.Cause(err))
+	ta.Error(hdr.EncodeBits(w, []interface{}{1}))
+	ta.Equal(ErrUnknownEltType, errors.Cause(hdr.EncodeBits(w, 1)))
+	ta.Equal(0, w.Len())
+
+	_, err = BitTuple()
+	ta.Error(err)
+}
+
+func reflectUint64(v interface{}) uint64 {
+	rv, _ := bitIntValue(v)
+	if isSignedKind(rv.Kind()) {
+		return uint64(rv.Int())
+	}
+	return rv.Uint()
+}
+
+func reflectInt64(v interface{}) int64 {
+	rv, _ := bitIntValue(v)
+	if isSignedKind(rv.Kind()) {
+		return rv.Int()
+	}
+	return int64(rv.Uint())
+}
@@ -0,0 +1,330 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// bitmapHeaderSize is the size of the number of bits and the number of ones.
+const bitmapHeaderSize = 8
+
+// bitmapRankWords is the number of words between two rank index entries.
+const bitmapRankWords = 8
+
+// Bitmap is a Codec of []bool that takes 1 bit for each element.
+// Get, Rank and Select work on the encoded bytes without decoding.
+//
+// Bits are stored in uint64 words, element i is the (i%64)-th least
+// significant bit of word i/64.
+// A rank index of the number of ones before every 8 words, i.e., 512 bits,
+// makes Rank O(1) and Select O(log n), at the cost of 1/16 bit per bit.
+//
+// The encoded layout is, integers are little-endian:
+//
+//     n      uint32          // number of bits
+//     ones   uint32          // number of ones
+//     words  uint64 * ceil(n/64)
+//     ranks  uint32 * ceil(words/8)
+type Bitmap struct{}
+
+// Encode converts a []bool to bytes.
+func (c Bitmap) Encode(d interface{}) []byte {
+	bs := d.([]bool)
+
+	words := make([]uint64, (len(bs)+63)/64)
+	for i, x := range bs {
+		if x {
+			words[i/64] |= 1 << uint(i%64)
+		}
+	}
+	return appendBitmap(nil, words, len(bs))
+}
+
+// Decode converts bytes to a []bool.
+// It returns number bytes consumed and the slice.
+func (c Bitmap) Decode(b []byte) (int, interface{}) {
+	bm := readBitmap(b)
+	rst := make([]bool, bm.n)
+	for i := range rst {
+		rst[i] = bm.get(i)
+	}
+	return bm.size, rst
+}
+
+// Size returns the size in byte after encoding v.
+func (c Bitmap) Size(d interface{}) int {
+	return bitmapSize(len(d.([]bool)))
+}
+
+// EncodedSize returns size of the encoded bitmap.
+func (c Bitmap) EncodedSize(b []byte) int {
+	return bitmapSize(int(binary.LittleEndian.Uint32(b)))
+}
+
+// NeededSize returns the encoded size if the header is complete.
+func (c Bitmap) NeededSize(b []byte) int {
+	if len(b) < bitmapHeaderSize {
+		return bitmapHeaderSize
+	}
+	return c.EncodedSize(b)
+}
+
+// Len returns the number of bits.
+func (c Bitmap) Len(b []byte) int {
+	return int(binary.LittleEndian.Uint32(b))
+}
+
+// Count returns the number of true.
+func (c Bitmap) Count(b []byte) int {
+	return int(binary.LittleEndian.Uint32(b[4:]))
+}
+
+// Get returns the i-th bit.
+// It panics if i is out of range.
+func (c Bitmap) Get(b []byte, i int) bool {
+	bm := readBitmap(b)
+	if i < 0 || i >= bm.n {
+		panic(errors.Errorf("index out of range: %d, len: %d", i, bm.n))
+	}
+	return bm.get(i)
+}
+
+// Rank returns the number of true in the first i bits.
+// It panics if i is not in [0, n].
+func (c Bitmap) Rank(b []byte, i int) int {
+	bm := readBitmap(b)
+	if i < 0 || i > bm.n {
+		panic(errors.Errorf("index out of range: %d, len: %d", i, bm.n))
+	}
+	return bm.rank(i)
+}
+
+// Select returns the index of the k-th true, counting from 0.
+// It panics if k is not less than Count.
+func (c Bitmap) Select(b []byte, k int) int {
+	return readBitmap(b).sel(k)
+}
+
+// Bitset is a Codec of sets of small unsigned integers, such as ids in a
+// small range, that takes 1 bit for every integer in [0, max].
+// It accepts []uint8, []uint16, []uint32 or []uint64 in any order, and decodes
+// to a sorted slice of the same type without duplicates.
+//
+// The encoded layout is a uint8 reflect.Kind of the element followed by a
+// Bitmap in which bit x is set if x is in the set.
+type Bitset struct{}
+
+// Encode converts a slice of unsigned integers to bytes.
+// It panics if d is not a slice of fixed size unsigned integers, or an element
+// does not fit in 32 bits.
+func (c Bitset) Encode(d interface{}) []byte {
+	vals, kind := toBitsetValues(d)
+
+	n := 0
+	for _, v := range vals {
+		if int(v) >= n {
+			n = int(v) + 1
+		}
+	}
+
+	words := make([]uint64, (n+63)/64)
+	for _, v := range vals {
+		words[v/64] |= 1 << (v % 64)
+	}
+	return appendBitmap([]byte{byte(kind)}, words, n)
+}
+
+// Decode converts bytes to a sorted slice of unsigned integers.
+// It returns number bytes consumed and the slice.
+func (c Bitset) Decode(b []byte) (int, interface{}) {
+	bm := readBitmap(b[1:])
+	vals := make([]uint64, 0, bm.ones)
+	for i := 0; i < bm.n; i++ {
+		if bm.get(i) {
+			vals = append(vals, uint64(i))
+		}
+	}
+	return 1 + bm.size, fromOrderedUint64s(reflect.Kind(b[0]), vals)
+}
+
+// Size returns the size in byte after encoding v.
+func (c Bitset) Size(d interface{}) int {
+	vals, _ := toBitsetValues(d)
+
+	n := 0
+	for _, v := range vals {
+		if int(v) >= n {
+			n = int(v) + 1
+		}
+	}
+	return 1 + bitmapSize(n)
+}
+
+// EncodedSize returns size of the encoded set.
+func (c Bitset) EncodedSize(b []byte) int {
+	return 1 + Bitmap{}.EncodedSize(b[1:])
+}
+
+// NeededSize returns the encoded size if the header is complete.
+func (c Bitset) NeededSize(b []byte) int {
+	if len(b) < 1+bitmapHeaderSize {
+		return 1 + bitmapHeaderSize
+	}
+	return c.EncodedSize(b)
+}
+
+// Len returns the number of elements in the set.
+func (c Bitset) Len(b []byte) int {
+	return Bitmap{}.Count(b[1:])
+}
+
+// Contains returns true if x is in the set.
+func (c Bitset) Contains(b []byte, x uint64) bool {
+	bm := readBitmap(b[1:])
+	return x < uint64(bm.n) && bm.get(int(x))
+}
+
+// Get returns the i-th bit, i.e., true if i is in the set.
+// Bits beyond the largest element are zeros.
+// It panics if i is negative.
+func (c Bitset) Get(b []byte, i int) bool {
+	if i < 0 {
+		panic(errors.Errorf("index out of range: %d", i))
+	}
+	bm := readBitmap(b[1:])
+	return i < bm.n && bm.get(i)
+}
+
+// Rank returns the number of elements less than x.
+func (c Bitset) Rank(b []byte, x uint64) int {
+	bm := readBitmap(b[1:])
+	if x >= uint64(bm.n) {
+		return bm.ones
+	}
+	return bm.rank(int(x))
+}
+
+// Select returns the k-th smallest element, counting from 0.
+// It panics if k is not less than Len.
+func (c Bitset) Select(b []byte, k int) uint64 {
+	return uint64(readBitmap(b[1:]).sel(k))
+}
+
+func toBitsetValues(d interface{}) ([]uint64, reflect.Kind) {
+	vals, kind, err := toOrderedUint64s(d)
+	if err != nil {
+		panic(err)
+	}
+	if isSignedKind(kind) {
+		panic(errors.Wrapf(ErrUnknownEltType, "type: %T, want: unsigned", d))
+	}
+	for _, v := range vals {
+		if v >= math.MaxUint32 {
+			panic(errors.Wrapf(ErrOutOfRange, "%d for bitset", v))
+		}
+	}
+	return vals, kind
+}
+
+func bitmapSize(n int) int {
+	nw := (n + 63) / 64
+	return bitmapHeaderSize + 8*nw + 4*((nw+bitmapRankWords-1)/bitmapRankWords)
+}
+
+// appendBitmap appends n bits in words with header and rank index to b.
+func appendBitmap(b []byte, words []uint64, n int) []byte {
+
+	ones := 0
+	var ranks []byte
+	for i, w := range words {
+		if i%bitmapRankWords == 0 {
+			ranks = appendU32(ranks, ones)
+		}
+		ones += bits.OnesCount64(w)
+	}
+
+	b = appendU32(b, n)
+	b = appendU32(b, ones)
+	for _, w := range words {
+		b = appendU64(b, w)
+	}
+	return append(b, ranks...)
+}
+
+// bitmap is a view of an encoded Bitmap.
+type bitmap struct {
+	n     int
+	ones  int
+	nw    int
+	words []byte
+	ranks []byte
+	size  int
+}
+
+func readBitmap(b []byte) *bitmap {
+	bm := &bitmap{
+		n:    int(binary.LittleEndian.Uint32(b)),
+		ones: int(binary.LittleEndian.Uint32(b[4:])),
+	}
+	bm.nw = (bm.n + 63) / 64
+	bm.size = bitmapSize(bm.n)
+
+	rankStart := bitmapHeaderSize + 8*bm.nw
+	bm.words = b[bitmapHeaderSize:rankStart]
+	bm.ranks = b[rankStart:bm.size]
+	return bm
+}
+
+func (bm *bitmap) word(i int) uint64 {
+	return binary.LittleEndian.Uint64(bm.words[i*8:])
+}
+
+func (bm *bitmap) get(i int) bool {
+	return bm.word(i/64)&(1<<uint(i%64)) != 0
+}
+
+// rank returns the number of ones in [0, i).
+func (bm *bitmap) rank(i int) int {
+	wi := i / 64
+	if wi == bm.nw {
+		return bm.ones
+	}
+
+	blk := wi / bitmapRankWords
+	r := int(binary.LittleEndian.Uint32(bm.ranks[blk*4:]))
+	for j := blk * bitmapRankWords; j < wi; j++ {
+		r += bits.OnesCount64(bm.word(j))
+	}
+	return r + bits.OnesCount64(bm.word(wi)&(1<<uint(i%64)-1))
+}
+
+// sel returns the index of the k-th one.
+func (bm *bitmap) sel(k int) int {
+	if k < 0 || k >= bm.ones {
+		panic(errors.Errorf("select out of range: %d, ones: %d", k, bm.ones))
+	}
+
+	nblk := len(bm.ranks) / 4
+	// the last block with less than k+1 ones before it.
+	blk := sort.Search(nblk, func(i int) bool {
+		return int(binary.LittleEndian.Uint32(bm.ranks[i*4:])) > k
+	}) - 1
+
+	k -= int(binary.LittleEndian.Uint32(bm.ranks[blk*4:]))
+	for wi := blk * bitmapRankWords; ; wi++ {
+		w := bm.word(wi)
+		cnt := bits.OnesCount64(w)
+		if k < cnt {
+			for ; k > 0; k-- {
+				w &= w - 1
+			}
+			return wi*64 + bits.TrailingZeros64(w)
+		}
+		k -= cnt
+	}
+}
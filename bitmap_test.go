@@ -0,0 +1,138 @@
+package qcodec
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBitmap(t *testing.T) {
+
+	ta := require.New(t)
+
+	rnd := rand.New(rand.NewSource(1))
+
+	random := make([]bool, 3000)
+	for i := range random {
+		random[i] = rnd.Intn(3) == 0
+	}
+
+	cases := [][]bool{
+		{},
+		{true},
+		{false, false},
+		{true, false, true, true},
+		make([]bool, 64),
+		random,
+	}
+
+	c := Bitmap{}
+
+	for i, input := range cases {
+		b := c.Encode(input)
+		ta.Equal(len(b), c.Size(input), "%d-th: len: %d", i+1, len(input))
+		ta.Equal(len(b), c.EncodedSize(b))
+		ta.Equal(len(b), c.NeededSize(b))
+
+		n, v := c.Decode(append(b, 1))
+		ta.Equal(len(b), n)
+		ta.Equal(input, v)
+
+		ta.Equal(len(input), c.Len(b))
+
+		ones := 0
+		for j, x := range input {
+			ta.Equal(x, c.Get(b, j))
+			ta.Equal(ones, c.Rank(b, j))
+			if x {
+				ta.Equal(j, c.Select(b, ones))
+				ones++
+			}
+		}
+		ta.Equal(ones, c.Rank(b, len(input)))
+		ta.Equal(ones, c.Count(b))
+
+		testPanic(t, func() { c.Select(b, ones) }, "select out of range")
+		testPanic(t, func() { c.Get(b, len(input)) }, "get out of range")
+		testPanic(t, func() { c.Rank(b, len(input)+1) }, "rank out of range")
+	}
+}
+
+func TestBitmapSize(t *testing.T) {
+
+	ta := require.New(t)
+
+	c := Bitmap{}
+
+	// 1 million flags: 1 bit each plus the rank index
+	b := c.Encode(make([]bool, 1000000))
+	ta.Equal(bitmapHeaderSize+15625*8+1954*4, len(b))
+
+	ta.Equal(bitmapHeaderSize, c.NeededSize(nil))
+	ta.Equal(len(b), c.NeededSize(b[:bitmapHeaderSize]))
+}
+
+func TestBitset(t *testing.T) {
+
+	ta := require.New(t)
+
+	cases := []struct {
+		input interface{}
+		want  interface{}
+	}{
+		{[]uint32{}, []uint32{}},
+		{[]uint8{0}, []uint8{0}},
+		{[]uint16{9, 3, 9, 1000}, []uint16{3, 9, 1000}},
+		{[]uint64{64, 63, 0, 511, 512}, []uint64{0, 63, 64, 511, 512}},
+	}
+
+	c := Bitset{}
+
+	for i, tc := range cases {
+		b := c.Encode(tc.input)
+		ta.Equal(len(b), c.Size(tc.input), "%d-th: case: %+v", i+1, tc)
+		ta.Equal(len(b), c.EncodedSize(b))
+		ta.Equal(len(b), c.NeededSize(b))
+
+		n, v := c.Decode(append(b, 1))
+		ta.Equal(len(b), n)
+		ta.Equal(tc.want, v)
+
+		members, _, _ := toOrderedUint64s(tc.want)
+		ta.Equal(len(members), c.Len(b))
+
+		for k, x := range members {
+			ta.True(c.Contains(b, x))
+			ta.True(c.Get(b, int(x)))
+			ta.Equal(k, c.Rank(b, x))
+			ta.Equal(x, c.Select(b, k))
+		}
+		ta.Equal(len(members), c.Rank(b, 1<<40))
+		ta.False(c.Contains(b, 1<<40))
+	}
+
+	b := c.Encode([]uint32{5, 7})
+	ta.False(c.Contains(b, 6))
+	ta.Equal(1, c.Rank(b, 6))
+	ta.Equal(0, c.Rank(b, 0))
+
+	for i := 0; i < 10; i++ {
+		ta.Equal(i == 5 || i == 7, c.Get(b, i), "bit: %d", i)
+	}
+	ta.False(c.Get(b, 1<<20))
+	testPanic(t, func() { c.Get(b, -1) }, "negative index")
+
+	ta.Equal(1+bitmapHeaderSize, c.NeededSize(b[:3]))
+
+	testPanic(t, func() { c.Encode([]int32{1}) }, "signed")
+	testPanic(t, func() { c.Encode([]uint64{1 << 32}) }, "too large")
+
+	func() {
+		defer func() {
+			ta.Equal(ErrUnknownEltType, errors.Cause(recover().(error)))
+		}()
+		c.Encode([]int8{1})
+	}()
+}
@@ -0,0 +1,113 @@
+package qcodec
+
+import (
+	"github.com/pkg/errors"
+)
+
+// BitWriter appends bits to a byte slice, most significant bit first.
+// The zero value is an empty BitWriter ready to use.
+type BitWriter struct {
+	buf []byte
+	// free is the number of unused low bits in the last byte.
+	free uint
+}
+
+// WriteBit appends one bit.
+func (w *BitWriter) WriteBit(bit bool) {
+	if w.free == 0 {
+		w.buf = append(w.buf, 0)
+		w.free = 8
+	}
+	w.free--
+	if bit {
+		w.buf[len(w.buf)-1] |= 1 << w.free
+	}
+}
+
+// WriteBits appends the lower n bits of v, higher bits first.
+// Bits of v above n are ignored.
+// n must not be greater than 64.
+func (w *BitWriter) WriteBits(v uint64, n uint) {
+	for n > 0 {
+		if w.free == 0 {
+			w.buf = append(w.buf, 0)
+			w.free = 8
+		}
+
+		k := w.free
+		if k > n {
+			k = n
+		}
+
+		n -= k
+		w.free -= k
+		x := byte(v>>n) & byte(1<<k-1)
+		w.buf[len(w.buf)-1] |= x << w.free
+	}
+}
+
+// Bytes returns the written bits, the last byte is padded with zeros.
+func (w *BitWriter) Bytes() []byte {
+	return w.buf
+}
+
+// Len returns the number of bits written.
+func (w *BitWriter) Len() int {
+	return len(w.buf)*8 - int(w.free)
+}
+
+// BitReader reads bits written by BitWriter.
+type BitReader struct {
+	buf []byte
+	// pos is the index of the next bit to read.
+	pos uint64
+}
+
+// NewBitReader creates a *BitReader reading from the first bit of b.
+func NewBitReader(b []byte) *BitReader {
+	return &BitReader{buf: b}
+}
+
+// ReadBit reads one bit.
+// It returns an error wrapping ErrShortBuffer if there is no more bit.
+func (r *BitReader) ReadBit() (bool, error) {
+	v, err := r.ReadBits(1)
+	return v == 1, err
+}
+
+// ReadBits reads n bits as the lower bits of the returned value.
+// It returns an error wrapping ErrShortBuffer if there are less than n bits.
+// n must not be greater than 64.
+func (r *BitReader) ReadBits(n uint) (uint64, error) {
+	if r.pos+uint64(n) > uint64(len(r.buf))*8 {
+		return 0, errors.Wrapf(ErrShortBuffer, "need bits: %d, got: %d", n, uint64(len(r.buf))*8-r.pos)
+	}
+
+	var v uint64
+	for n > 0 {
+		idx := r.pos / 8
+		used := uint(r.pos % 8)
+
+		k := 8 - used
+		if k > n {
+			k = n
+		}
+
+		x := r.buf[idx] >> (8 - used - k) & byte(1<<k-1)
+		v = v<<k | uint64(x)
+
+		n -= k
+		r.pos += uint64(k)
+	}
+	return v, nil
+}
+
+// Offset returns the number of bits read.
+func (r *BitReader) Offset() int {
+	return int(r.pos)
+}
+
+// Remaining returns the number of bits not read.
+func (r *BitReader) Remaining() int {
+	return len(r.buf)*8 - int(r.pos)
+}
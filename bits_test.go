@@ -0,0 +1,83 @@
+package qcodec
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBitWriter(t *testing.T) {
+
+	ta := require.New(t)
+
+	w := &BitWriter{}
+	w.WriteBit(true)
+	w.WriteBits(0x5, 3)
+	w.WriteBits(0x1ff, 9)
+	w.WriteBit(false)
+	w.WriteBit(true)
+
+	// 1 101 111111111 0 1
+	ta.Equal([]byte{0xdf, 0xfa}, w.Bytes())
+
+	r := NewBitReader(w.Bytes())
+
+	b, err := r.ReadBit()
+	ta.NoError(err)
+	ta.True(b)
+
+	v, err := r.ReadBits(3)
+	ta.NoError(err)
+	ta.Equal(uint64(5), v)
+
+	v, err = r.ReadBits(9)
+	ta.NoError(err)
+	ta.Equal(uint64(0x1ff), v)
+
+	v, err = r.ReadBits(2)
+	ta.NoError(err)
+	ta.Equal(uint64(1), v)
+
+	// padding
+	v, err = r.ReadBits(1)
+	ta.NoError(err)
+	ta.Equal(uint64(0), v)
+
+	_, err = r.ReadBits(1)
+	ta.Equal(ErrShortBuffer, errors.Cause(err))
+}
+
+func TestBitWriterRandom(t *testing.T) {
+
+	ta := require.New(t)
+
+	rnd := rand.New(rand.NewSource(1))
+
+	type field struct {
+		v uint64
+		n uint
+	}
+
+	var fields []field
+	w := &BitWriter{}
+	for i := 0; i < 1000; i++ {
+		n := uint(rnd.Intn(65))
+		v := rnd.Uint64()
+		if n < 64 {
+			v &= 1<<n - 1
+		}
+		fields = append(fields, field{v, n})
+
+		// higher bits are ignored
+		w.WriteBits(v|^(1<<n-1)*uint64(rnd.Intn(2)), n)
+	}
+
+	r := NewBitReader(w.Bytes())
+	for i, f := range fields {
+		v, err := r.ReadBits(f.n)
+		ta.NoError(err)
+		ta.Equal(f.v, v, "%d-th: width: %d", i, f.n)
+	}
+}
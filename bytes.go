@@ -1,5 +1,7 @@
 package qcodec
 
+import "bytes"
+
 // Bytes converts a byte slice into fixed length slice.
 // Result slice length is defined by Bytes.size .
 type Bytes struct {
@@ -27,3 +29,8 @@ func (c Bytes) Size(d interface{}) int {
 func (c Bytes) EncodedSize(b []byte) int {
 	return c.size
 }
+
+// Compare compares two fixed length slices in byte order.
+func (c Bytes) Compare(a, b []byte) int {
+	return bytes.Compare(a[:c.size], b[:c.size])
+}
@@ -0,0 +1,212 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"hash/crc64"
+	"hash/fnv"
+
+	"github.com/pkg/errors"
+)
+
+// ChecksumAlgo specifies the checksum used by ChecksumCodec.
+type ChecksumAlgo int
+
+const (
+	// CRC32C is CRC-32 with Castagnoli polynomial, 4 bytes.
+	CRC32C ChecksumAlgo = iota
+	// CRC32IEEE is CRC-32 with IEEE polynomial, 4 bytes.
+	CRC32IEEE
+	// CRC64ECMA is CRC-64 with ECMA polynomial, 8 bytes.
+	CRC64ECMA
+	// FNV64a is the non-cryptographic hash FNV-1a, 8 bytes.
+	FNV64a
+)
+
+var (
+	crc32cTable    = crc32.MakeTable(crc32.Castagnoli)
+	crc64ECMATable = crc64.MakeTable(crc64.ECMA)
+)
+
+// String returns the name of the algorithm.
+func (a ChecksumAlgo) String() string {
+	switch a {
+	case CRC32C:
+		return "crc32c"
+	case CRC32IEEE:
+		return "crc32"
+	case CRC64ECMA:
+		return "crc64-ecma"
+	case FNV64a:
+		return "fnv64a"
+	}
+	return fmt.Sprintf("ChecksumAlgo(%d)", int(a))
+}
+
+// size returns the number of bytes of the checksum, or 0 if a is unknown.
+func (a ChecksumAlgo) size() int {
+	switch a {
+	case CRC32C, CRC32IEEE:
+		return 4
+	case CRC64ECMA, FNV64a:
+		return 8
+	}
+	return 0
+}
+
+func (a ChecksumAlgo) sum(b []byte) uint64 {
+	switch a {
+	case CRC32C:
+		return uint64(crc32.Checksum(b, crc32cTable))
+	case CRC32IEEE:
+		return uint64(crc32.ChecksumIEEE(b))
+	case CRC64ECMA:
+		return crc64.Checksum(b, crc64ECMATable)
+	case FNV64a:
+		h := fnv.New64a()
+		_, _ = h.Write(b)
+		return h.Sum64()
+	}
+	panic(errors.Wrapf(ErrUnknownAlgo, "checksum algo: %d", int(a)))
+}
+
+// ChecksumError is returned when the checksum of an encoded value does not
+// match.
+// It is an ErrCorrupted.
+type ChecksumError struct {
+	Algo ChecksumAlgo
+	// Expected is the checksum stored with the value.
+	Expected uint64
+	// Actual is the checksum calculated from the value.
+	Actual uint64
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("%v: %s checksum mismatch: expected: %#x, actual: %#x",
+		ErrCorrupted, e.Algo, e.Expected, e.Actual)
+}
+
+// Cause returns ErrCorrupted, for github.com/pkg/errors.Cause.
+func (e *ChecksumError) Cause() error {
+	return ErrCorrupted
+}
+
+// Unwrap returns ErrCorrupted, for errors.Is.
+func (e *ChecksumError) Unwrap() error {
+	return ErrCorrupted
+}
+
+// ChecksumCodec appends a checksum to the encoding of an inner Codec and
+// verifies it when decoding.
+//
+// An encoded value is the inner encoding followed by the little-endian
+// checksum of it.
+// If the inner Codec is fixed size, so is ChecksumCodec.
+type ChecksumCodec struct {
+	inner   Codec
+	algo    ChecksumAlgo
+	sumSize int
+}
+
+// Checksummed creates a *ChecksumCodec that protects the encoding of inner
+// with a checksum algo.
+func Checksummed(inner Codec, algo ChecksumAlgo) (*ChecksumCodec, error) {
+	sz := algo.size()
+	if sz == 0 {
+		return nil, errors.Wrapf(ErrUnknownAlgo, "checksum algo: %d", int(algo))
+	}
+
+	return &ChecksumCodec{
+		inner:   inner,
+		algo:    algo,
+		sumSize: sz,
+	}, nil
+}
+
+// Encode encodes d with the inner Codec and appends the checksum.
+func (c *ChecksumCodec) Encode(d interface{}) []byte {
+	b := c.inner.Encode(d)
+	sum := c.algo.sum(b)
+
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], sum)
+	return append(b, buf[:c.sumSize]...)
+}
+
+// Decode verifies the checksum and decodes a value.
+// It returns number bytes consumed and the value decoded by the inner Codec.
+// It panics with a *ChecksumError if the checksum does not match.
+func (c *ChecksumCodec) Decode(b []byte) (int, interface{}) {
+	n, v, err := c.DecodeChecked(b)
+	if err != nil {
+		panic(err)
+	}
+	return n, v
+}
+
+// DecodeChecked is the same as Decode except it returns an error, which is
+// a *ChecksumError if the checksum does not match.
+func (c *ChecksumCodec) DecodeChecked(b []byte) (int, interface{}, error) {
+	n, err := c.NeededSizeChecked(b)
+	if err != nil {
+		return 0, nil, err
+	}
+	if n > len(b) {
+		return 0, nil, errors.Wrapf(ErrShortBuffer, "need: %d, got: %d", n, len(b))
+	}
+
+	data := b[:n-c.sumSize]
+
+	var buf [8]byte
+	copy(buf[:], b[n-c.sumSize:n])
+	expected := binary.LittleEndian.Uint64(buf[:])
+
+	actual := c.algo.sum(data)
+	if actual != expected {
+		return 0, nil, &ChecksumError{
+			Algo:     c.algo,
+			Expected: expected,
+			Actual:   actual,
+		}
+	}
+
+	_, v, err := decodeChecked(c.inner, data)
+	if err != nil {
+		return 0, nil, err
+	}
+	return n, v, nil
+}
+
+// Size returns the size of the inner encoding plus the checksum size.
+func (c *ChecksumCodec) Size(d interface{}) int {
+	return c.inner.Size(d) + c.sumSize
+}
+
+// EncodedSize returns the size of the inner encoding plus the checksum size.
+func (c *ChecksumCodec) EncodedSize(b []byte) int {
+	return c.inner.EncodedSize(b) + c.sumSize
+}
+
+// NeededSize returns the size needed by the inner Codec plus the checksum
+// size.
+// It panics if the inner Codec finds a corrupted size header.
+func (c *ChecksumCodec) NeededSize(b []byte) int {
+	return mustNeededSize(c.NeededSizeChecked(b))
+}
+
+// NeededSizeChecked is the same as NeededSize except it returns the error of
+// the inner Codec.
+func (c *ChecksumCodec) NeededSizeChecked(b []byte) (int, error) {
+	n, err := neededSizeChecked(c.inner, b)
+	if err != nil {
+		return 0, err
+	}
+	return n + c.sumSize, nil
+}
+
+// Compare compares two values with the inner Codec.
+// Checksums are not verified.
+func (c *ChecksumCodec) Compare(a, b []byte) int {
+	return CompareEncoded(c.inner, a, b)
+}
@@ -0,0 +1,173 @@
+package qcodec
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksummed(t *testing.T) {
+
+	ta := require.New(t)
+
+	xy, err := NewTypeCodec(typeXY{})
+	ta.NoError(err)
+
+	cases := []struct {
+		inner   Codec
+		algo    ChecksumAlgo
+		input   interface{}
+		sumSize int
+	}{
+		{String16{}, CRC32C, "", 4},
+		{String16{}, CRC32C, "hello", 4},
+		{String16{}, CRC32IEEE, "hello", 4},
+		{String16{}, CRC64ECMA, "hello", 8},
+		{String16{}, FNV64a, "hello", 8},
+		{U32{}, CRC32C, uint32(5), 4},
+		{xy, FNV64a, typeXY{1, -1}, 8},
+	}
+
+	for i, c := range cases {
+		m, err := Checksummed(c.inner, c.algo)
+		ta.NoError(err)
+
+		b := m.Encode(c.input)
+		want := c.inner.Size(c.input) + c.sumSize
+		ta.Equal(want, len(b), "%d-th: case: %+v", i+1, c)
+		ta.Equal(want, m.Size(c.input))
+		ta.Equal(want, m.EncodedSize(b))
+		ta.Equal(want, m.NeededSize(b))
+
+		n, v := m.Decode(append(b, 9))
+		ta.Equal(want, n)
+		ta.Equal(c.input, v)
+
+		ta.Equal(0, m.Compare(b, b))
+
+		// every single bit flip is detected
+		for j := 0; j < len(b)*8; j++ {
+			bad := append([]byte{}, b...)
+			bad[j/8] ^= 1 << uint(j%8)
+
+			// a flip in a length header may result in a short buffer
+			_, _, err := m.DecodeChecked(bad)
+			cause := pkgerrors.Cause(err)
+			ta.True(cause == ErrCorrupted || cause == ErrShortBuffer, "%d-th: bit: %d: %v", i+1, j, err)
+		}
+	}
+
+	// value checked
+	m, err := Checksummed(String16{}, CRC32C)
+	ta.NoError(err)
+	b := m.Encode("abc")
+	ta.Equal(crc32.Checksum([]byte{0, 3, 'a', 'b', 'c'}, crc32.MakeTable(crc32.Castagnoli)),
+		uint32(b[5])|uint32(b[6])<<8|uint32(b[7])<<16|uint32(b[8])<<24)
+
+	n, ok := fixedSize(m)
+	ta.False(ok)
+	ta.Equal(0, n)
+
+	mu, err := Checksummed(U32{}, CRC64ECMA)
+	ta.NoError(err)
+	n, ok = fixedSize(mu)
+	ta.True(ok)
+	ta.Equal(12, n)
+}
+
+func TestChecksumError(t *testing.T) {
+
+	ta := require.New(t)
+
+	_, err := Checksummed(U8{}, ChecksumAlgo(100))
+	ta.Equal(ErrUnknownAlgo, pkgerrors.Cause(err))
+	ta.Equal("ChecksumAlgo(100)", ChecksumAlgo(100).String())
+
+	m, err := Checksummed(String16{}, CRC32C)
+	ta.NoError(err)
+
+	b := m.Encode("hello")
+	b[3] = 'x'
+
+	_, _, err = m.DecodeChecked(b)
+	var ce *ChecksumError
+	ta.True(errors.As(err, &ce))
+	ta.Equal(CRC32C, ce.Algo)
+	ta.Equal(uint64(crc32.Checksum([]byte("\x00\x05hello"), crc32cTable)), ce.Expected)
+	ta.Equal(uint64(crc32.Checksum([]byte("\x00\x05hxllo"), crc32cTable)), ce.Actual)
+	ta.True(errors.Is(err, ErrCorrupted))
+	ta.Contains(err.Error(), "crc32c checksum mismatch")
+
+	testPanic(t, func() { m.Decode(b) }, "checksum mismatch")
+
+	// through Reader the typed error is kept
+	_, err = NewReader(b).Read(m)
+	ta.True(errors.As(err, &ce))
+	ta.Equal(ErrCorrupted, pkgerrors.Cause(err))
+
+	_, _, err = m.DecodeChecked(b[:4])
+	ta.Equal(ErrShortBuffer, pkgerrors.Cause(err))
+
+	// corrupted size header of the inner Codec
+	cm, err := Compressed(String16{}, Flate, 1)
+	ta.NoError(err)
+	m, err = Checksummed(cm, CRC32C)
+	ta.NoError(err)
+
+	badPrefix := bytes.Repeat([]byte{0xff}, 11)
+	_, err = m.NeededSizeChecked(badPrefix)
+	ta.Equal(ErrCorrupted, pkgerrors.Cause(err))
+	testPanic(t, func() { m.NeededSize(badPrefix) }, "corrupted inner prefix")
+
+	_, _, err = m.DecodeChecked(badPrefix)
+	ta.Equal(ErrCorrupted, pkgerrors.Cause(err))
+	testPanic(t, func() { m.Decode(badPrefix) }, "corrupted inner prefix")
+
+	_, err = NewReader(badPrefix).Read(m)
+	ta.Equal(ErrCorrupted, pkgerrors.Cause(err))
+	_, _, err = NewDecoder(m).Feed(badPrefix)
+	ta.Equal(ErrCorrupted, pkgerrors.Cause(err))
+}
+
+func TestChecksummedCompose(t *testing.T) {
+
+	ta := require.New(t)
+
+	xy, err := NewTypeCodec(typeXY{})
+	ta.NoError(err)
+
+	m, err := Checksummed(xy, CRC32C)
+	ta.NoError(err)
+
+	elts := []typeXY{{1, 2}, {3, 4}, {-5, 6}}
+	a, err := NewArray(m, elts)
+	ta.NoError(err)
+	for i, e := range elts {
+		ta.Equal(e, a.Get(i))
+	}
+
+	buf := encodeAll(m, typeXY{0, 1}, typeXY{0, 3}, typeXY{2, 0})
+	i, found, err := SearchExact(buf, m, typeXY{0, 3})
+	ta.NoError(err)
+	ta.True(found)
+	ta.Equal(1, i)
+
+	// wrap a compressed codec
+	cm, err := Compressed(String16{}, Flate, 1)
+	ta.NoError(err)
+	m, err = Checksummed(cm, CRC64ECMA)
+	ta.NoError(err)
+
+	d := NewDecoder(m)
+	var got []interface{}
+	for _, x := range append(m.Encode("foo"), m.Encode("bar")...) {
+		vals, _, err := d.Feed([]byte{x})
+		ta.NoError(err)
+		got = append(got, vals...)
+	}
+	ta.Equal([]interface{}{"foo", "bar"}, got)
+}
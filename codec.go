@@ -3,6 +3,7 @@
 package qcodec
 
 import (
+	"bytes"
 	"errors"
 	"reflect"
 )
@@ -13,6 +14,10 @@ var (
 	// ErrUnknownEltType indicates a type this package does not support.
 	ErrUnknownEltType = errors.New("element type is unknown")
 
+	// ErrCorrupted indicates the encoded data is damaged and can not be
+	// decoded.
+	ErrCorrupted = errors.New("encoded data is corrupted")
+
 	// ErrNotFixedSize indicates the size of value of a type can not be
 	// determined by its type.
 	// Such slice of interface.
@@ -39,6 +44,73 @@ type Codec interface {
 	EncodedSize([]byte) int
 }
 
+// A Comparer is a Codec that compares two encoded values without the need of
+// converting them back to interface{}.
+// Built-in Codec such as U32, String16 or TypeCodec implement Comparer.
+type Comparer interface {
+	// Compare returns -1, 0 or 1 if the value encoded in a is less than, equal
+	// to or greater than the one in b.
+	Compare(a, b []byte) int
+}
+
+// A CheckedDecoder is a Codec that verifies the input when decoding, such as
+// one with compression or checksum.
+// Its Decode panics with the error DecodeChecked would return.
+type CheckedDecoder interface {
+	// DecodeChecked is the same as Decode except it returns an error instead
+	// of panicking if the input is malformed or corrupted.
+	DecodeChecked([]byte) (int, interface{}, error)
+}
+
+// A StreamSizer is a Codec that is able to tell the encoded size from an
+// incomplete byte stream.
+//
+// Codecs with a fixed size or a size header should implement it so that a
+// value can be decoded from partially received data.
+type StreamSizer interface {
+	// NeededSize returns the encoded size of the value at the start of b, if
+	// it can be determined with the bytes in b.
+	// Otherwise it returns a number greater than len(b): the minimal number of
+	// bytes to have before calling it again.
+	NeededSize(b []byte) int
+}
+
+// A CheckedSizer is a StreamSizer that verifies the size header, such as a
+// length prefix.
+// Its NeededSize panics with the error NeededSizeChecked would return.
+type CheckedSizer interface {
+	// NeededSizeChecked is the same as NeededSize except it returns an error
+	// instead of panicking if the size header is corrupted.
+	NeededSizeChecked(b []byte) (int, error)
+}
+
+// neededSizeChecked returns the encoded size of the value at the start of b,
+// or the minimal number of bytes required to determine it, if it is greater
+// than len(b).
+//
+// A Codec that does not implement StreamSizer is asked with EncodedSize. If it
+// panics, it is considered one more byte is required.
+//
+// An error is returned only if c is a CheckedSizer and the size header is
+// corrupted.
+func neededSizeChecked(c Codec, b []byte) (n int, err error) {
+	if s, ok := c.(CheckedSizer); ok {
+		return s.NeededSizeChecked(b)
+	}
+
+	if s, ok := c.(StreamSizer); ok {
+		return s.NeededSize(b), nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			n, err = len(b)+1, nil
+		}
+	}()
+
+	return c.EncodedSize(b), nil
+}
+
 // CodecOf returns a `Codec` implementation for type `e`
 func CodecOf(e interface{}) (Codec, error) {
 	k := reflect.ValueOf(e).Kind()
@@ -83,6 +155,46 @@ func CodecByKind(k reflect.Kind) (Codec, error) {
 	return m, nil
 }
 
+// decodeChecked decodes b with c and returns an error if c is a
+// CheckedDecoder and the input is invalid.
+func decodeChecked(c Codec, b []byte) (int, interface{}, error) {
+	if d, ok := c.(CheckedDecoder); ok {
+		return d.DecodeChecked(b)
+	}
+	n, v := c.Decode(b)
+	return n, v, nil
+}
+
+// fixedSize returns the encoded size of every value of a Codec and true, if
+// the Codec has a fixed size encoding.
+func fixedSize(c Codec) (int, bool) {
+	switch t := c.(type) {
+	case U8, I8:
+		return 1, true
+	case U16, I16:
+		return 2, true
+	case U32, I32:
+		return 4, true
+	case U64, I64:
+		return 8, true
+	case Int, Dummy, Bytes, *TypeCodec, *UintNCodec, *IntNCodec, *BitTupleCodec:
+		return t.EncodedSize(nil), true
+	case *ChecksumCodec:
+		if n, ok := fixedSize(t.inner); ok {
+			return n + t.sumSize, true
+		}
+	case *SignedCodec:
+		if n, ok := fixedSize(t.inner); ok {
+			return n + t.sigSize, true
+		}
+	case *ReedSolomonCodec:
+		if n, ok := fixedSize(t.inner); ok {
+			return t.size(n), true
+		}
+	}
+	return 0, false
+}
+
 // String16 converts uint16 to slice of 2 bytes and back.
 type String16 struct{}
 
@@ -117,3 +229,20 @@ func (s String16) EncodedSize(b []byte) int {
 	l := int(b[0])<<8 + int(b[1])
 	return 2 + l
 }
+
+// Compare compares two encoded strings by their content.
+// The length header is not compared, since it does not preserve order.
+func (s String16) Compare(a, b []byte) int {
+	la := int(a[0])<<8 + int(a[1])
+	lb := int(b[0])<<8 + int(b[1])
+	return bytes.Compare(a[2:2+la], b[2:2+lb])
+}
+
+// NeededSize returns 2 if the length header is incomplete, otherwise it
+// returns the encoded size.
+func (s String16) NeededSize(b []byte) int {
+	if len(b) < 2 {
+		return 2
+	}
+	return s.EncodedSize(b)
+}
@@ -120,3 +120,37 @@ func TestGetSliceEltEncoder(t *testing.T) {
 		}
 	}
 }
+
+func TestFixedSize(t *testing.T) {
+
+	xy, _ := NewTypeCodec(typeXY{})
+	u13, _ := UintN(13)
+	i3, _ := IntN(3)
+	bt, _ := BitTuple(u13, i3)
+
+	cases := []struct {
+		input     Codec
+		want      int
+		wantFixed bool
+	}{
+		{U8{}, 1, true},
+		{I16{}, 2, true},
+		{U32{}, 4, true},
+		{I64{}, 8, true},
+		{Dummy{}, 0, true},
+		{Bytes{size: 3}, 3, true},
+		{xy, 8, true},
+		{u13, 2, true},
+		{i3, 1, true},
+		{bt, 2, true},
+		{String16{}, 0, false},
+	}
+
+	for i, c := range cases {
+		n, fixed := fixedSize(c.input)
+		if n != c.want || fixed != c.wantFixed {
+			t.Fatalf("%d-th: input: %#v; want: %v %v; actual: %v %v",
+				i+1, c.input, c.want, c.wantFixed, n, fixed)
+		}
+	}
+}
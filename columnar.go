@@ -0,0 +1,397 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// columnarTag is the struct tag key to specify the codec of a column.
+const columnarTag = "qcodec"
+
+// ErrUnknownColumn indicates a column name is not found in a ColumnarCodec.
+var ErrUnknownColumn = errors.New("unknown column")
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// ColumnarCodec is a Codec of slices of structs that stores every field in its
+// own column, encoded by a slice Codec for the field, so that values of a
+// field are compressed together and a column is decoded without others.
+//
+// The Codec of a column is specified by the struct tag "qcodec", or chosen by
+// the field type if there is no tag:
+//
+//     tag        codec                       field type
+//     auto       Auto                        integers, default
+//     for        FrameOfRef                  integers
+//     linear     Linear                      integers
+//     rle        RLECodec                    integers
+//     delta      DeltaSeq                    int64, uint64, time.Time
+//     dod        DeltaSeq with DeltaOfDelta  int64, uint64, time.Time, default
+//                                            for time.Time
+//     float      FloatSeries                 float64, default
+//     bitmap     Bitmap                      bool, default
+//     dict       Dict                        string, default
+//     raw        TypeCodec of every value    any fixed size type, default for
+//                                            others
+//     -          the field is skipped
+//
+// Unexported fields are skipped.
+// An int or uint field is stored as int64 or uint64, thus int64 and uint64
+// tags also apply to it.
+// A field of a named type, such as "type Status string", is converted to and
+// from the underlying type.
+//
+// The encoded layout is, integers are little-endian:
+//
+//     n        uint32 // number of structs
+//     ncols    uint32 // number of columns
+//     ends     uint32 * ncols // end offset of every column in data
+//     data     columns in field order
+type ColumnarCodec struct {
+	typ  reflect.Type
+	cols []*column
+	// byName is index of cols by field name.
+	byName map[string]int
+}
+
+// column is a field encoded in a column.
+type column struct {
+	name  string
+	field int
+	codec Codec
+	// eltType is the element type of the slice codec accepts.
+	eltType reflect.Type
+}
+
+// NewColumnar creates a *ColumnarCodec for slices of the struct type of
+// "zero".
+func NewColumnar(zero interface{}) (*ColumnarCodec, error) {
+	typ := reflect.Indirect(reflect.ValueOf(zero)).Type()
+	if typ.Kind() != reflect.Struct {
+		return nil, errors.Wrapf(ErrUnknownEltType, "type: %v, want: struct", typ)
+	}
+
+	c := &ColumnarCodec{
+		typ:    typ,
+		byName: make(map[string]int),
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag := f.Tag.Get(columnarTag)
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+
+		col, err := newColumn(f, tag)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "field: %s", f.Name)
+		}
+		col.field = i
+
+		c.byName[f.Name] = len(c.cols)
+		c.cols = append(c.cols, col)
+	}
+
+	return c, nil
+}
+
+// newColumn chooses the slice Codec for a field by tag or by type.
+func newColumn(f reflect.StructField, tag string) (*column, error) {
+	col := &column{name: f.Name}
+
+	k := f.Type.Kind()
+
+	// int and uint are stored in 64-bit columns.
+	switch k {
+	case reflect.Int:
+		k = reflect.Int64
+	case reflect.Uint:
+		k = reflect.Uint64
+	}
+
+	isInt := false
+	switch k {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		isInt = true
+	}
+
+	if tag == "" {
+		switch {
+		case isInt:
+			tag = "auto"
+		case f.Type == timeType:
+			tag = "dod"
+		case k == reflect.Float64:
+			tag = "float"
+		case k == reflect.Bool:
+			tag = "bitmap"
+		case k == reflect.String:
+			tag = "dict"
+		default:
+			tag = "raw"
+		}
+	}
+
+	mismatch := errors.Wrapf(ErrUnknownEltType, "tag: %q, type: %v", tag, f.Type)
+
+	switch tag {
+	case "auto", "for", "linear", "rle":
+		if !isInt {
+			return nil, mismatch
+		}
+		col.eltType, _ = intKindType(k)
+		switch tag {
+		case "auto":
+			col.codec = Auto{}
+		case "for":
+			col.codec = FrameOfRef{}
+		case "linear":
+			col.codec = Linear{}
+		default:
+			col.codec = autoRLE(k)
+		}
+
+	case "delta", "dod":
+		switch {
+		case f.Type == timeType:
+			col.eltType = timeType
+		case k == reflect.Int64 || k == reflect.Uint64:
+			col.eltType, _ = intKindType(k)
+		default:
+			return nil, mismatch
+		}
+		col.codec = DeltaSeq{}
+		if tag == "dod" {
+			col.codec = DeltaSeq{Mode: DeltaOfDelta}
+		}
+
+	case "float":
+		if k != reflect.Float64 {
+			return nil, mismatch
+		}
+		col.eltType, col.codec = reflect.TypeOf(float64(0)), FloatSeries{}
+
+	case "bitmap":
+		if k != reflect.Bool {
+			return nil, mismatch
+		}
+		col.eltType, col.codec = reflect.TypeOf(false), Bitmap{}
+
+	case "dict":
+		if k != reflect.String {
+			return nil, mismatch
+		}
+		col.eltType, col.codec = reflect.TypeOf(""), Dict{}
+
+	case "raw":
+		elt, err := NewTypeCodecByType(f.Type, defaultEndian)
+		if err != nil {
+			return nil, err
+		}
+		col.eltType, col.codec = f.Type, &typeColumn{elt: elt}
+
+	default:
+		return nil, errors.Errorf("unknown column codec: %q", tag)
+	}
+
+	return col, nil
+}
+
+// Columns returns the names of the columns in order.
+func (c *ColumnarCodec) Columns() []string {
+	rst := make([]string, len(c.cols))
+	for i, col := range c.cols {
+		rst[i] = col.name
+	}
+	return rst
+}
+
+// ColumnCodec returns the slice Codec of a column.
+func (c *ColumnarCodec) ColumnCodec(name string) (Codec, error) {
+	i, ok := c.byName[name]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownColumn, "name: %s", name)
+	}
+	return c.cols[i].codec, nil
+}
+
+// Encode converts a slice of the struct to bytes.
+// It panics if d is not a slice of the struct type.
+func (c *ColumnarCodec) Encode(d interface{}) []byte {
+	sl := reflect.ValueOf(d)
+	if sl.Kind() != reflect.Slice || sl.Type().Elem() != c.typ {
+		panic(errors.Wrapf(ErrUnknownEltType, "type: %T, want: []%v", d, c.typ))
+	}
+
+	n := sl.Len()
+
+	var ends, data []byte
+	for _, col := range c.cols {
+		vals := reflect.MakeSlice(reflect.SliceOf(col.eltType), n, n)
+		for i := 0; i < n; i++ {
+			vals.Index(i).Set(sl.Index(i).Field(col.field).Convert(col.eltType))
+		}
+		data = append(data, col.codec.Encode(vals.Interface())...)
+		ends = appendU32(ends, len(data))
+	}
+
+	rst := make([]byte, 0, 8+len(ends)+len(data))
+	rst = appendU32(rst, n)
+	rst = appendU32(rst, len(c.cols))
+	rst = append(rst, ends...)
+	return append(rst, data...)
+}
+
+// Decode converts bytes to a slice of the struct.
+// It returns number bytes consumed and the slice.
+func (c *ColumnarCodec) Decode(b []byte) (int, interface{}) {
+	v, err := c.DecodeColumns(b, c.Columns()...)
+	if err != nil {
+		panic(err)
+	}
+	return c.EncodedSize(b), v
+}
+
+// Size returns the size in byte after encoding v.
+func (c *ColumnarCodec) Size(d interface{}) int {
+	return len(c.Encode(d))
+}
+
+// EncodedSize returns size of the encoded slice.
+func (c *ColumnarCodec) EncodedSize(b []byte) int {
+	ncols := int(binary.LittleEndian.Uint32(b[4:]))
+	dataStart := 8 + 4*ncols
+	if ncols == 0 {
+		return dataStart
+	}
+	return dataStart + int(binary.LittleEndian.Uint32(b[dataStart-4:]))
+}
+
+// NeededSize returns the encoded size if the header is complete.
+func (c *ColumnarCodec) NeededSize(b []byte) int {
+	if len(b) < 8 {
+		return 8
+	}
+	dataStart := 8 + 4*int(binary.LittleEndian.Uint32(b[4:]))
+	if len(b) < dataStart {
+		return dataStart
+	}
+	return c.EncodedSize(b)
+}
+
+// Len returns the number of structs.
+func (c *ColumnarCodec) Len(b []byte) int {
+	return int(binary.LittleEndian.Uint32(b))
+}
+
+// DecodeColumn decodes a column and returns a slice of the field type.
+// Other columns are not decoded.
+func (c *ColumnarCodec) DecodeColumn(b []byte, name string) (interface{}, error) {
+	i, ok := c.byName[name]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownColumn, "name: %s", name)
+	}
+
+	p, err := c.columnData(b, i)
+	if err != nil {
+		return nil, err
+	}
+
+	col := c.cols[i]
+	_, vals := col.codec.Decode(p)
+
+	fieldType := c.typ.Field(col.field).Type
+	src := reflect.ValueOf(vals)
+	if src.Type().Elem() == fieldType {
+		return vals, nil
+	}
+
+	dst := reflect.MakeSlice(reflect.SliceOf(fieldType), src.Len(), src.Len())
+	for k := 0; k < src.Len(); k++ {
+		dst.Index(k).Set(src.Index(k).Convert(fieldType))
+	}
+	return dst.Interface(), nil
+}
+
+// DecodeColumns decodes the named columns and returns a slice of the struct
+// with only these fields set.
+// Other columns are not decoded.
+func (c *ColumnarCodec) DecodeColumns(b []byte, names ...string) (interface{}, error) {
+	n := c.Len(b)
+	rst := reflect.MakeSlice(reflect.SliceOf(c.typ), n, n)
+
+	for _, name := range names {
+		vals, err := c.DecodeColumn(b, name)
+		if err != nil {
+			return nil, err
+		}
+
+		src := reflect.ValueOf(vals)
+		if src.Len() != n {
+			return nil, errors.Wrapf(ErrMalformed, "column %s length: %d, want: %d", name, src.Len(), n)
+		}
+
+		field := c.cols[c.byName[name]].field
+		for i := 0; i < n; i++ {
+			rst.Index(i).Field(field).Set(src.Index(i))
+		}
+	}
+	return rst.Interface(), nil
+}
+
+// columnData returns the encoded i-th column.
+func (c *ColumnarCodec) columnData(b []byte, i int) ([]byte, error) {
+	ncols := int(binary.LittleEndian.Uint32(b[4:]))
+	if ncols != len(c.cols) {
+		return nil, errors.Wrapf(ErrMalformed, "columns: %d, want: %d", ncols, len(c.cols))
+	}
+
+	dataStart := 8 + 4*ncols
+	start := 0
+	if i > 0 {
+		start = int(binary.LittleEndian.Uint32(b[8+4*(i-1):]))
+	}
+	end := int(binary.LittleEndian.Uint32(b[8+4*i:]))
+	return b[dataStart+start : dataStart+end], nil
+}
+
+// typeColumn is a slice Codec of a fixed size type encoded by TypeCodec.
+//
+// The encoded layout is a little-endian uint32 count followed by the
+// values.
+type typeColumn struct {
+	elt *TypeCodec
+}
+
+func (c *typeColumn) Encode(d interface{}) []byte {
+	sl := reflect.ValueOf(d)
+	rst := make([]byte, 0, 4+sl.Len()*c.elt.size)
+	rst = appendU32(rst, sl.Len())
+	for i := 0; i < sl.Len(); i++ {
+		rst = append(rst, c.elt.Encode(sl.Index(i).Interface())...)
+	}
+	return rst
+}
+
+func (c *typeColumn) Decode(b []byte) (int, interface{}) {
+	n := int(binary.LittleEndian.Uint32(b))
+	sl := reflect.MakeSlice(reflect.SliceOf(c.elt.typ), n, n)
+	for i := 0; i < n; i++ {
+		_, v := c.elt.Decode(b[4+i*c.elt.size:])
+		sl.Index(i).Set(reflect.ValueOf(v))
+	}
+	return c.EncodedSize(b), sl.Interface()
+}
+
+func (c *typeColumn) Size(d interface{}) int {
+	return 4 + reflect.ValueOf(d).Len()*c.elt.size
+}
+
+func (c *typeColumn) EncodedSize(b []byte) int {
+	return 4 + int(binary.LittleEndian.Uint32(b))*c.elt.size
+}
@@ -0,0 +1,223 @@
+package qcodec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type testStatus string
+
+type testRow struct {
+	ID      uint64 `qcodec:"delta"`
+	Offset  uint32 `qcodec:"linear"`
+	Level   int8   `qcodec:"rle"`
+	Score   float64
+	OK      bool
+	Status  testStatus
+	At      time.Time
+	Pos     typeXY
+	F32     float32
+	Count   int32
+	Ignored string `qcodec:"-"`
+	private int
+}
+
+func newTestRows(n int) []testRow {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := make([]testRow, n)
+	for i := range rows {
+		rows[i] = testRow{
+			ID:     uint64(1000 + i),
+			Offset: uint32(i * 64),
+			Level:  int8(i / 100),
+			Score:  float64(i%10) / 4,
+			OK:     i%3 == 0,
+			Status: testStatus([]string{"new", "done"}[i%2]),
+			At:     start.Add(time.Duration(i) * time.Minute),
+			Pos:    typeXY{int32(i), -int32(i)},
+			F32:    float32(i) / 2,
+			Count:  int32(i % 7),
+		}
+	}
+	return rows
+}
+
+func TestColumnar(t *testing.T) {
+
+	ta := require.New(t)
+
+	c, err := NewColumnar(testRow{})
+	ta.NoError(err)
+
+	ta.Equal([]string{"ID", "Offset", "Level", "Score", "OK", "Status", "At", "Pos", "F32", "Count"}, c.Columns())
+
+	for _, n := range []int{0, 1, 500} {
+		rows := newTestRows(n)
+
+		b := c.Encode(rows)
+		ta.Equal(len(b), c.Size(rows), "n: %d", n)
+		ta.Equal(len(b), c.EncodedSize(b))
+		ta.Equal(len(b), c.NeededSize(b))
+		ta.Equal(n, c.Len(b))
+
+		m, v := c.Decode(append(b, 1))
+		ta.Equal(len(b), m)
+		ta.Equal(rows, v)
+	}
+
+	// columns compress better than rows
+	rows := newTestRows(500)
+	tc, _ := NewTypeCodec(struct {
+		ID     uint64
+		Offset uint32
+		Level  int8
+		Score  float64
+		OK     bool
+		At     int64
+		Pos    typeXY
+		F32    float32
+		Count  int32
+	}{})
+	ta.True(len(c.Encode(rows)) < tc.size*len(rows)/2, "size: %d", len(c.Encode(rows)))
+}
+
+func TestColumnarDecodeColumns(t *testing.T) {
+
+	ta := require.New(t)
+
+	c, err := NewColumnar(testRow{})
+	ta.NoError(err)
+
+	rows := newTestRows(300)
+	b := c.Encode(rows)
+
+	ids, err := c.DecodeColumn(b, "ID")
+	ta.NoError(err)
+	st, err := c.DecodeColumn(b, "Status")
+	ta.NoError(err)
+	for i, r := range rows {
+		ta.Equal(r.ID, ids.([]uint64)[i])
+		ta.Equal(r.Status, st.([]testStatus)[i])
+	}
+
+	v, err := c.DecodeColumns(b, "Score", "At")
+	ta.NoError(err)
+	for i, r := range v.([]testRow) {
+		ta.Equal(testRow{Score: rows[i].Score, At: rows[i].At}, r)
+	}
+
+	_, err = c.DecodeColumn(b, "Ignored")
+	ta.Equal(ErrUnknownColumn, errors.Cause(err))
+	_, err = c.DecodeColumns(b, "ID", "nope")
+	ta.Equal(ErrUnknownColumn, errors.Cause(err))
+
+	// other columns are not read: corrupting one does not affect others
+	codec, err := c.ColumnCodec("Level")
+	ta.NoError(err)
+	ta.IsType(&RLECodec{}, codec)
+
+	bad := append([]byte{}, b...)
+	p, err := c.columnData(bad, c.byName["Level"])
+	ta.NoError(err)
+	for i := range p {
+		p[i] = 0xff
+	}
+	v, err = c.DecodeColumns(bad, "ID", "Offset", "Status")
+	ta.NoError(err)
+	for i, r := range v.([]testRow) {
+		ta.Equal(rows[i].Offset, r.Offset)
+	}
+}
+
+func TestColumnarNativeInt(t *testing.T) {
+
+	ta := require.New(t)
+
+	type row struct {
+		ID  int
+		N   uint `qcodec:"for"`
+		Seq int  `qcodec:"delta"`
+	}
+
+	c, err := NewColumnar(row{})
+	ta.NoError(err)
+
+	codec, err := c.ColumnCodec("ID")
+	ta.NoError(err)
+	ta.Equal(Auto{}, codec)
+
+	rows := make([]row, 200)
+	for i := range rows {
+		rows[i] = row{ID: i*7 - 100, N: uint(i % 13), Seq: 1000 + i*3}
+	}
+
+	b := c.Encode(rows)
+	_, v := c.Decode(b)
+	ta.Equal(rows, v)
+
+	ids, err := c.DecodeColumn(b, "ID")
+	ta.NoError(err)
+	ta.Equal(-100, ids.([]int)[0])
+}
+
+func TestColumnarError(t *testing.T) {
+
+	ta := require.New(t)
+
+	_, err := NewColumnar(1)
+	ta.Equal(ErrUnknownEltType, errors.Cause(err))
+
+	cases := []interface{}{
+		struct {
+			X string `qcodec:"for"`
+		}{},
+		struct {
+			X int32 `qcodec:"delta"`
+		}{},
+		struct {
+			X float32 `qcodec:"float"`
+		}{},
+		struct {
+			X int `qcodec:"bitmap"`
+		}{},
+		struct {
+			X bool `qcodec:"dict"`
+		}{},
+		struct{ X []int }{},
+	}
+	for i, zero := range cases {
+		_, err := NewColumnar(zero)
+		ta.Error(err, "%d-th: %T", i+1, zero)
+	}
+
+	_, err = NewColumnar(struct {
+		X int32 `qcodec:"zip"`
+	}{})
+	ta.Error(err)
+
+	c, err := NewColumnar(testRow{})
+	ta.NoError(err)
+	testPanic(t, func() { c.Encode([]typeXY{}) }, "wrong type")
+
+	b := c.Encode(newTestRows(3))
+	ta.Equal(8, c.NeededSize(b[:3]))
+	ta.Equal(8+4*10, c.NeededSize(b[:8]))
+
+	// a different struct with fewer columns
+	other, err := NewColumnar(struct{ ID uint64 }{})
+	ta.NoError(err)
+	_, err = other.DecodeColumn(b, "ID")
+	ta.Equal(ErrMalformed, errors.Cause(err))
+
+	// empty struct
+	empty, err := NewColumnar(struct{}{})
+	ta.NoError(err)
+	eb := empty.Encode(make([]struct{}, 3))
+	ta.Equal(8, len(eb))
+	_, v := empty.Decode(eb)
+	ta.Equal(make([]struct{}, 3), v)
+	ta.Equal(3, empty.Len(eb))
+}
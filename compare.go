@@ -0,0 +1,116 @@
+package qcodec
+
+import (
+	"sort"
+)
+
+// CompareEncoded compares two values encoded with c.
+// It returns -1, 0 or 1 if the value in a is less than, equal to or greater
+// than the one in b.
+//
+// If c implements Comparer, c.Compare is used.
+// Otherwise both values are decoded and compared natively: integers, floats,
+// strings and arrays or structs of them are supported.
+func CompareEncoded(c Codec, a, b []byte) int {
+	if cmp, ok := c.(Comparer); ok {
+		return cmp.Compare(a, b)
+	}
+
+	_, x := c.Decode(a)
+	_, y := c.Decode(b)
+	return compareValues(x, y)
+}
+
+// SortEncoded sorts in place a buffer of concatenated elements encoded with a
+// fixed size Codec c.
+func SortEncoded(buf []byte, c Codec) error {
+	size, err := searchEltSize(buf, c)
+	if err != nil {
+		return err
+	}
+	if size == 0 {
+		return nil
+	}
+
+	sort.Sort(&encodedSlice{
+		codec: c,
+		buf:   buf,
+		size:  size,
+		tmp:   make([]byte, size),
+	})
+	return nil
+}
+
+// IsSortedEncoded reports whether a buffer of concatenated elements encoded
+// with a fixed size Codec c is sorted.
+func IsSortedEncoded(buf []byte, c Codec) (bool, error) {
+	size, err := searchEltSize(buf, c)
+	if err != nil {
+		return false, err
+	}
+	if size == 0 {
+		return true, nil
+	}
+
+	return sort.IsSorted(&encodedSlice{
+		codec: c,
+		buf:   buf,
+		size:  size,
+	}), nil
+}
+
+// SortRecords sorts encoded records of Codec c.
+// Records may be var-length, such as ones encoded by String16.
+// The sort is stable.
+func SortRecords(recs [][]byte, c Codec) {
+	sort.SliceStable(recs, func(i, j int) bool {
+		return CompareEncoded(c, recs[i], recs[j]) < 0
+	})
+}
+
+// MergeRecords merges two sorted list of records of Codec c into a new sorted
+// list.
+// If two records are equal, the one in "a" comes first.
+func MergeRecords(c Codec, a, b [][]byte) [][]byte {
+	rst := make([][]byte, 0, len(a)+len(b))
+
+	for len(a) > 0 && len(b) > 0 {
+		if CompareEncoded(c, b[0], a[0]) < 0 {
+			rst = append(rst, b[0])
+			b = b[1:]
+		} else {
+			rst = append(rst, a[0])
+			a = a[1:]
+		}
+	}
+
+	rst = append(rst, a...)
+	rst = append(rst, b...)
+	return rst
+}
+
+// encodedSlice implements sort.Interface for a buffer of fixed size elements.
+type encodedSlice struct {
+	codec Codec
+	buf   []byte
+	size  int
+	tmp   []byte
+}
+
+func (s *encodedSlice) Len() int {
+	return len(s.buf) / s.size
+}
+
+func (s *encodedSlice) Less(i, j int) bool {
+	return CompareEncoded(s.codec, s.elt(i), s.elt(j)) < 0
+}
+
+func (s *encodedSlice) Swap(i, j int) {
+	copy(s.tmp, s.elt(i))
+	copy(s.elt(i), s.elt(j))
+	copy(s.elt(j), s.tmp)
+}
+
+func (s *encodedSlice) elt(i int) []byte {
+	return s.buf[i*s.size : (i+1)*s.size]
+}
@@ -0,0 +1,123 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComparer(t *testing.T) {
+
+	ta := require.New(t)
+
+	kv, err := NewTypeCodec(typeKV{}, binary.BigEndian)
+	ta.NoError(err)
+
+	xy, err := NewTypeCodec(typeXY{})
+	ta.NoError(err)
+
+	// every pair is in ascending order
+	cases := []struct {
+		codec Codec
+		a, b  interface{}
+	}{
+		{U8{}, uint8(1), uint8(2)},
+		{I8{}, int8(-1), int8(0)},
+		{U16{}, uint16(0xff), uint16(0x100)},
+		{I16{}, int16(-0x100), int16(1)},
+		{U32{}, uint32(0xff), uint32(0x100)},
+		{I32{}, int32(-1), int32(0)},
+		{U64{}, uint64(0xff), uint64(0x100)},
+		{I64{}, int64(-1), int64(1)},
+		{Int{}, -1, 1},
+		{String16{}, "b", "ba"},
+		{String16{}, "az", "b"},
+		{Bytes{size: 2}, []byte("ab"), []byte("ba")},
+		{kv, typeKV{1, 0xffff}, typeKV{2, 0}},
+		{xy, typeXY{-1, 5}, typeXY{0, -5}},
+		{xy, typeXY{1, 0x100}, typeXY{1, 0x101}},
+	}
+
+	for i, c := range cases {
+		a := c.codec.Encode(c.a)
+		b := c.codec.Encode(c.b)
+
+		cmp := c.codec.(Comparer)
+		ta.Equal(-1, cmp.Compare(a, b), "%d-th: case: %+v", i+1, c)
+		ta.Equal(1, cmp.Compare(b, a), "%d-th: case: %+v", i+1, c)
+		ta.Equal(0, cmp.Compare(a, a), "%d-th: case: %+v", i+1, c)
+
+		// without Comparer: decode and compare
+
+		nc := noStreamSizer{c.codec}
+		ta.Equal(-1, CompareEncoded(nc, a, b), "%d-th: case: %+v", i+1, c)
+		ta.Equal(1, CompareEncoded(nc, b, a), "%d-th: case: %+v", i+1, c)
+		ta.Equal(0, CompareEncoded(nc, b, b), "%d-th: case: %+v", i+1, c)
+	}
+
+	ta.Equal(0, Dummy{}.Compare(nil, nil))
+}
+
+func TestSortEncoded(t *testing.T) {
+
+	ta := require.New(t)
+
+	xy, err := NewTypeCodec(typeXY{})
+	ta.NoError(err)
+
+	buf := encodeAll(xy, typeXY{3, 1}, typeXY{-1, 2}, typeXY{3, 0}, typeXY{0, 0})
+
+	sorted, err := IsSortedEncoded(buf, xy)
+	ta.NoError(err)
+	ta.False(sorted)
+
+	ta.NoError(SortEncoded(buf, xy))
+	ta.Equal(encodeAll(xy, typeXY{-1, 2}, typeXY{0, 0}, typeXY{3, 0}, typeXY{3, 1}), buf)
+
+	sorted, err = IsSortedEncoded(buf, xy)
+	ta.NoError(err)
+	ta.True(sorted)
+
+	buf = encodeAll(U32{}, uint32(0x100), uint32(2), uint32(0x1ff))
+	ta.NoError(SortEncoded(buf, U32{}))
+	ta.Equal(encodeAll(U32{}, uint32(2), uint32(0x100), uint32(0x1ff)), buf)
+
+	ta.NoError(SortEncoded(nil, Dummy{}))
+
+	ta.Equal(ErrNotFixedSize, errors.Cause(SortEncoded(nil, String16{})))
+	ta.Equal(ErrMalformed, errors.Cause(SortEncoded([]byte{1}, U16{})))
+	_, err = IsSortedEncoded(nil, String16{})
+	ta.Equal(ErrNotFixedSize, errors.Cause(err))
+}
+
+func TestSortAndMergeRecords(t *testing.T) {
+
+	ta := require.New(t)
+
+	m := String16{}
+	enc := func(ss ...string) [][]byte {
+		var rst [][]byte
+		for _, s := range ss {
+			rst = append(rst, m.Encode(s))
+		}
+		return rst
+	}
+
+	recs := enc("bb", "a", "c", "ab", "")
+	SortRecords(recs, m)
+	ta.Equal(enc("", "a", "ab", "bb", "c"), recs)
+
+	a := enc("a", "c", "e")
+	b := enc("b", "c", "d", "f", "g")
+
+	merged := MergeRecords(m, a, b)
+	ta.Equal(enc("a", "b", "c", "c", "d", "e", "f", "g"), merged)
+
+	// equal records: the one in "a" comes first
+	ta.True(&merged[2][0] == &a[1][0])
+
+	ta.Equal(enc("a"), MergeRecords(m, enc("a"), nil))
+	ta.Equal(enc("a"), MergeRecords(m, nil, enc("a")))
+}
@@ -0,0 +1,289 @@
+package qcodec
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// CompressAlgo specifies the compression format used by CompressedCodec.
+type CompressAlgo int
+
+const (
+	// Flate is raw DEFLATE defined in RFC 1951.
+	Flate CompressAlgo = iota
+	// Zlib is DEFLATE with zlib header and checksum, defined in RFC 1950.
+	Zlib
+	// Gzip is DEFLATE with gzip header and checksum, defined in RFC 1952.
+	// It does not support preset dictionary.
+	Gzip
+)
+
+// maxDecompressedSize is the max size of an inner encoding a CompressedCodec
+// decompresses. A larger expansion is rejected as corrupted, thus a crafted
+// value can not exhaust memory.
+const maxDecompressedSize = 64 << 20
+
+var (
+	// ErrUnknownAlgo indicates an algorithm is not supported.
+	ErrUnknownAlgo = errors.New("unknown algorithm")
+	// ErrDictUnsupported indicates the compression format does not support
+	// preset dictionary.
+	ErrDictUnsupported = errors.New("preset dictionary is not supported")
+)
+
+// CompressedCodec compresses the encoding of an inner Codec.
+//
+// An encoded value is a uvarint length prefix followed by the compressed
+// inner encoding.
+// EncodedSize reads only the prefix, thus it composes with Array, Reader or
+// any other container.
+//
+// A value that decompresses to more than 64 MiB is treated as corrupted.
+type CompressedCodec struct {
+	inner Codec
+	algo  CompressAlgo
+	level int
+	dict  []byte
+
+	// maxSize is the max size of a decompressed inner encoding.
+	maxSize int
+}
+
+// Compressed creates a *CompressedCodec that compresses the encoding of inner
+// with algorithm algo at compression level, such as flate.BestSpeed or
+// flate.DefaultCompression.
+//
+// An optional preset dictionary can be specified for Flate and Zlib.
+// The same dictionary must be used to decode.
+func Compressed(inner Codec, algo CompressAlgo, level int, dict ...[]byte) (*CompressedCodec, error) {
+	c := &CompressedCodec{
+		inner:   inner,
+		algo:    algo,
+		level:   level,
+		maxSize: maxDecompressedSize,
+	}
+
+	if len(dict) > 0 {
+		c.dict = dict[0]
+		if algo == Gzip {
+			return nil, errors.Wrapf(ErrDictUnsupported, "algo: gzip")
+		}
+	}
+
+	// check algo and level
+	w, err := c.newWriter(ioutil.Discard)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Encode compresses the encoding of d by the inner Codec.
+func (c *CompressedCodec) Encode(d interface{}) []byte {
+	var buf bytes.Buffer
+
+	w, err := c.newWriter(&buf)
+	if err != nil {
+		// algo and level are checked when creating
+		panic(err)
+	}
+
+	_, err = w.Write(c.inner.Encode(d))
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	rst := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+buf.Len())
+	n := binary.PutUvarint(rst, uint64(buf.Len()))
+	rst = append(rst[:n], buf.Bytes()...)
+	return rst
+}
+
+// Decode decompresses and decodes a value.
+// It returns number bytes consumed and the value decoded by the inner Codec.
+// It panics if the data is corrupted.
+func (c *CompressedCodec) Decode(b []byte) (int, interface{}) {
+	n, v, err := c.DecodeChecked(b)
+	if err != nil {
+		panic(err)
+	}
+	return n, v
+}
+
+// DecodeChecked is the same as Decode except it returns an error wrapping
+// ErrCorrupted if the data can not be decompressed.
+func (c *CompressedCodec) DecodeChecked(b []byte) (int, interface{}, error) {
+	n, raw, err := c.decompress(b)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	want, err := neededSizeChecked(c.inner, raw)
+	if err != nil {
+		return 0, nil, err
+	}
+	if want != len(raw) {
+		return 0, nil, errors.Wrapf(ErrCorrupted, "inner size: %d, want: %d", len(raw), want)
+	}
+
+	_, v, err := decodeChecked(c.inner, raw)
+	if err != nil {
+		return 0, nil, err
+	}
+	return n, v, nil
+}
+
+// Size returns the size in byte after encoding v.
+// It has to compress v to know the size.
+func (c *CompressedCodec) Size(d interface{}) int {
+	return len(c.Encode(d))
+}
+
+// EncodedSize returns the size of the encoded value by reading the length
+// prefix.
+func (c *CompressedCodec) EncodedSize(b []byte) int {
+	n, err := lenPrefixedSize(b)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// NeededSize returns the encoded size if the length prefix is complete.
+// It panics if the length prefix is corrupted.
+func (c *CompressedCodec) NeededSize(b []byte) int {
+	return lenPrefixedNeededSize(b)
+}
+
+// NeededSizeChecked is the same as NeededSize except it returns an error
+// wrapping ErrCorrupted if the length prefix is corrupted.
+func (c *CompressedCodec) NeededSizeChecked(b []byte) (int, error) {
+	return lenPrefixedNeededSizeChecked(b)
+}
+
+// Compare decompresses two values and compares them with the inner Codec.
+func (c *CompressedCodec) Compare(a, b []byte) int {
+	_, ra, err := c.decompress(a)
+	if err != nil {
+		panic(err)
+	}
+	_, rb, err := c.decompress(b)
+	if err != nil {
+		panic(err)
+	}
+	return CompareEncoded(c.inner, ra, rb)
+}
+
+// decompress returns the number of bytes consumed and the inner encoding.
+func (c *CompressedCodec) decompress(b []byte) (int, []byte, error) {
+	end, err := lenPrefixedSize(b)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(b) < end {
+		return 0, nil, errors.Wrapf(ErrShortBuffer, "need: %d, got: %d", end, len(b))
+	}
+	_, n := binary.Uvarint(b)
+
+	r, err := c.newReader(bytes.NewReader(b[n:end]))
+	if err != nil {
+		return 0, nil, errors.Wrapf(ErrCorrupted, "%v", err)
+	}
+
+	raw, err := ioutil.ReadAll(io.LimitReader(r, int64(c.maxSize)+1))
+	if err == nil {
+		err = r.Close()
+	}
+	if err != nil {
+		return 0, nil, errors.Wrapf(ErrCorrupted, "%v", err)
+	}
+	if len(raw) > c.maxSize {
+		return 0, nil, errors.Wrapf(ErrCorrupted, "decompressed size exceeds: %d", c.maxSize)
+	}
+
+	return end, raw, nil
+}
+
+func (c *CompressedCodec) newWriter(w io.Writer) (io.WriteCloser, error) {
+	switch c.algo {
+	case Flate:
+		return flate.NewWriterDict(w, c.level, c.dict)
+	case Zlib:
+		return zlib.NewWriterLevelDict(w, c.level, c.dict)
+	case Gzip:
+		return gzip.NewWriterLevel(w, c.level)
+	}
+	return nil, errors.Wrapf(ErrUnknownAlgo, "compress algo: %d", c.algo)
+}
+
+func (c *CompressedCodec) newReader(r io.Reader) (io.ReadCloser, error) {
+	switch c.algo {
+	case Flate:
+		return flate.NewReaderDict(r, c.dict), nil
+	case Zlib:
+		return zlib.NewReaderDict(r, c.dict)
+	case Gzip:
+		return gzip.NewReader(r)
+	}
+	return nil, errors.Wrapf(ErrUnknownAlgo, "compress algo: %d", c.algo)
+}
+
+// lenPrefixedSize returns the total size of a uvarint length prefixed value.
+// If the prefix is incomplete it returns an error wrapping ErrShortBuffer.
+func lenPrefixedSize(b []byte) (int, error) {
+	l, n := binary.Uvarint(b)
+	if n == 0 {
+		return 0, errors.Wrapf(ErrShortBuffer, "incomplete length prefix")
+	}
+	if n < 0 || l > uint64(maxInt-n) {
+		return 0, errors.Wrapf(ErrCorrupted, "invalid length prefix")
+	}
+	return n + int(l), nil
+}
+
+// lenPrefixedNeededSize implements StreamSizer.NeededSize for a uvarint length
+// prefixed value.
+// It panics if the prefix is corrupted.
+func lenPrefixedNeededSize(b []byte) int {
+	return mustNeededSize(lenPrefixedNeededSizeChecked(b))
+}
+
+// lenPrefixedNeededSizeChecked implements CheckedSizer.NeededSizeChecked for
+// a uvarint length prefixed value.
+// It returns an error wrapping ErrCorrupted if the prefix is corrupted.
+func lenPrefixedNeededSizeChecked(b []byte) (int, error) {
+	n, err := lenPrefixedSize(b)
+	if err != nil {
+		if errors.Cause(err) == ErrShortBuffer {
+			return len(b) + 1, nil
+		}
+		return 0, err
+	}
+	return n, nil
+}
+
+// mustNeededSize returns the size by a NeededSizeChecked, or panics with the
+// error.
+func mustNeededSize(n int, err error) int {
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+const maxInt = int(^uint(0) >> 1)
@@ -0,0 +1,213 @@
+package qcodec
+
+import (
+	"bytes"
+	"compress/flate"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressed(t *testing.T) {
+
+	ta := require.New(t)
+
+	long := strings.Repeat("hello world ", 200)
+	dict := []byte("hello world ")
+
+	xy, err := NewTypeCodec(typeXY{})
+	ta.NoError(err)
+
+	cases := []struct {
+		inner Codec
+		algo  CompressAlgo
+		level int
+		dict  [][]byte
+		input interface{}
+	}{
+		{String16{}, Flate, flate.DefaultCompression, nil, ""},
+		{String16{}, Flate, flate.BestCompression, nil, long},
+		{String16{}, Flate, flate.NoCompression, nil, long},
+		{String16{}, Flate, flate.BestSpeed, [][]byte{dict}, long},
+		{String16{}, Zlib, flate.DefaultCompression, nil, long},
+		{String16{}, Zlib, flate.DefaultCompression, [][]byte{dict}, long},
+		{String16{}, Gzip, flate.HuffmanOnly, nil, long},
+		{U64{}, Flate, flate.DefaultCompression, nil, uint64(0x0102030405)},
+		{xy, Zlib, flate.DefaultCompression, nil, typeXY{1, -2}},
+	}
+
+	for i, c := range cases {
+		m, err := Compressed(c.inner, c.algo, c.level, c.dict...)
+		ta.NoError(err)
+
+		b := m.Encode(c.input)
+		ta.Equal(len(b), m.Size(c.input), "%d-th: case: %+v", i+1, c)
+		ta.Equal(len(b), m.EncodedSize(b), "%d-th: case: %+v", i+1, c)
+		ta.Equal(len(b), m.NeededSize(b), "%d-th: case: %+v", i+1, c)
+
+		if c.level != flate.NoCompression && c.level != flate.HuffmanOnly && c.input == long {
+			ta.True(len(b) < len(long)/4, "%d-th: compressed size: %d", i+1, len(b))
+		}
+
+		n, v := m.Decode(append(b, 1, 2, 3))
+		ta.Equal(len(b), n)
+		ta.Equal(c.input, v, "%d-th: case: %+v", i+1, c)
+
+		ta.Equal(0, m.Compare(b, b))
+	}
+}
+
+func TestCompressedCompose(t *testing.T) {
+
+	ta := require.New(t)
+
+	m, err := Compressed(String16{}, Flate, flate.BestSpeed)
+	ta.NoError(err)
+
+	strs := []string{"a", strings.Repeat("b", 1000), "", strings.Repeat("cd", 300)}
+
+	// in an Array
+	a, err := NewArray(m, strs, 2)
+	ta.NoError(err)
+	for i, s := range strs {
+		ta.Equal(s, a.Get(i))
+	}
+
+	// with Builder and Reader
+	bd := NewBuilder(0)
+	for _, s := range strs {
+		bd.Append(m, s).Append(U8{}, uint8(7))
+	}
+	r := NewReader(bd.Bytes())
+	for _, s := range strs {
+		v, err := r.Read(m)
+		ta.NoError(err)
+		ta.Equal(s, v)
+		_, err = r.Skip(U8{})
+		ta.NoError(err)
+	}
+
+	// fed byte by byte
+	d := NewDecoder(m)
+	var got []interface{}
+	for _, s := range strs {
+		for _, x := range m.Encode(s) {
+			vals, _, err := d.Feed([]byte{x})
+			ta.NoError(err)
+			got = append(got, vals...)
+		}
+	}
+	ta.Equal(len(strs), len(got))
+	for i, s := range strs {
+		ta.Equal(s, got[i])
+	}
+
+	// compare by inner codec
+	ta.Equal(-1, m.Compare(m.Encode("a"), m.Encode("b")))
+	ta.Equal(-1, CompareEncoded(m, m.Encode("ab"), m.Encode("b")))
+}
+
+func TestCompressedError(t *testing.T) {
+
+	ta := require.New(t)
+
+	_, err := Compressed(String16{}, Gzip, flate.DefaultCompression, []byte("x"))
+	ta.Equal(ErrDictUnsupported, errors.Cause(err))
+
+	_, err = Compressed(String16{}, CompressAlgo(10), flate.DefaultCompression)
+	ta.Equal(ErrUnknownAlgo, errors.Cause(err))
+
+	_, err = Compressed(String16{}, Flate, 100)
+	ta.Error(err)
+
+	m, err := Compressed(String16{}, Zlib, flate.DefaultCompression)
+	ta.NoError(err)
+
+	b := m.Encode("hello hello hello")
+
+	// incomplete prefix
+	ta.Equal(1, m.NeededSize(nil))
+	ta.Equal(len(b), m.NeededSize(b[:1]))
+	testPanic(t, func() { m.EncodedSize(nil) }, "incomplete prefix")
+	testPanic(t, func() { m.NeededSize([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}) }, "overflow")
+
+	// corrupted prefix is an error in stream
+	badPrefix := bytes.Repeat([]byte{0x80}, 11)
+	_, err = m.NeededSizeChecked(badPrefix)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+
+	_, err = NewReader(badPrefix).Read(m)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+	_, ok := err.(*OffsetError)
+	ta.True(ok)
+
+	_, err = NewReader(badPrefix).Skip(m)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+
+	_, _, err = NewDecoder(m).Feed(badPrefix)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+
+	// corrupted payload
+	bad := append([]byte{}, b...)
+	bad[len(bad)-1] ^= 0xff
+
+	_, _, err = m.DecodeChecked(bad)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+	testPanic(t, func() { m.Decode(bad) }, "corrupted")
+
+	_, err = NewReader(bad).Read(m)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+
+	_, _, err = NewDecoder(m).Feed(bad)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+
+	// truncated
+	_, _, err = m.DecodeChecked(b[:len(b)-1])
+	ta.Equal(ErrShortBuffer, errors.Cause(err))
+
+	// wrong dictionary
+	md, err := Compressed(String16{}, Zlib, flate.DefaultCompression, []byte("hello"))
+	ta.NoError(err)
+	_, _, err = m.DecodeChecked(md.Encode("hello hello"))
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+
+	// inner encoding has extra bytes
+	mu, err := Compressed(U8{}, Flate, flate.DefaultCompression)
+	ta.NoError(err)
+	m16, err := Compressed(U16{}, Flate, flate.DefaultCompression)
+	ta.NoError(err)
+	_, _, err = mu.DecodeChecked(m16.Encode(uint16(1)))
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+}
+
+func TestCompressedExpansionLimit(t *testing.T) {
+
+	ta := require.New(t)
+
+	m, err := Compressed(Bytes{size: 1000}, Flate, flate.BestCompression)
+	ta.NoError(err)
+
+	b := m.Encode(make([]byte, 1000))
+
+	// a large expansion of a small value is rejected
+	m.maxSize = 999
+	_, _, err = m.DecodeChecked(b)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+	testPanic(t, func() { m.Decode(b) }, "expansion exceeds limit")
+
+	_, err = NewReader(b).Read(m)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+
+	m.maxSize = 1000
+	_, v, err := m.DecodeChecked(b)
+	ta.NoError(err)
+	ta.Equal(make([]byte, 1000), v)
+
+	// the default limit
+	mb, err := Compressed(Bytes{size: maxDecompressedSize + 1}, Flate, flate.BestSpeed)
+	ta.NoError(err)
+	_, _, err = mb.DecodeChecked(mb.Encode(make([]byte, maxDecompressedSize+1)))
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+}
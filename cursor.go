@@ -0,0 +1,180 @@
+package qcodec
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrShortBuffer indicates there are not enough bytes to decode a value.
+	ErrShortBuffer = errors.New("short buffer")
+	// ErrNegativeOffset indicates an offset to seek to is negative.
+	ErrNegativeOffset = errors.New("negative offset")
+)
+
+// OffsetError is returned by Reader with the offset in buffer where the error
+// occurs.
+type OffsetError struct {
+	// Offset is the position in buffer of the value failed to read.
+	Offset int
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *OffsetError) Error() string {
+	return fmt.Sprintf("offset: %d: %v", e.Offset, e.Err)
+}
+
+// Cause returns the underlying error, for github.com/pkg/errors.Cause.
+func (e *OffsetError) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the underlying error, for errors.Is and errors.As.
+func (e *OffsetError) Unwrap() error {
+	return e.Err
+}
+
+// Builder builds a byte slice by appending values with different Codec.
+//
+// The zero value is an empty Builder ready to use.
+type Builder struct {
+	buf []byte
+}
+
+// NewBuilder creates a *Builder with n bytes preallocated.
+func NewBuilder(n int) *Builder {
+	return &Builder{buf: make([]byte, 0, n)}
+}
+
+// Append encodes v with c and appends it.
+// It returns the Builder itself so that calls can be chained.
+func (b *Builder) Append(c Codec, v interface{}) *Builder {
+	b.buf = append(b.buf, c.Encode(v)...)
+	return b
+}
+
+// Bytes returns the built bytes.
+// The returned slice is shared with Builder until the next Append.
+func (b *Builder) Bytes() []byte {
+	return b.buf
+}
+
+// Len returns the number of bytes built.
+func (b *Builder) Len() int {
+	return len(b.buf)
+}
+
+// Reset discards all built bytes.
+func (b *Builder) Reset() {
+	b.buf = b.buf[:0]
+}
+
+// Reader is a cursor that decodes values with different Codec from a byte
+// slice one by one.
+//
+// Every step is bounds-checked.
+// An error returned by Reader is an *OffsetError with the offset of the value
+// that failed.
+// The cursor does not move if an error occurs.
+type Reader struct {
+	buf []byte
+	off int
+}
+
+// NewReader creates a *Reader at the start of b.
+func NewReader(b []byte) *Reader {
+	return &Reader{buf: b}
+}
+
+// Read decodes a value with c at the current offset and moves the cursor
+// forward.
+// A panic of c on malformed data is returned as an error wrapping
+// ErrCorrupted.
+func (r *Reader) Read(c Codec) (interface{}, error) {
+	n, err := r.peekSize(c)
+	if err != nil {
+		return nil, err
+	}
+
+	consumed, v, err := decodeRecovered(c, r.buf[r.off:r.off+n])
+	if err != nil {
+		return nil, r.errorf(err, "decode")
+	}
+	if consumed != n {
+		return nil, r.errorf(ErrSizeMismatch, "encoded size: %d, consumed: %d", n, consumed)
+	}
+
+	r.off += n
+	return v, nil
+}
+
+// Skip moves the cursor over a value of Codec c without decoding it.
+// It returns the number of bytes skipped.
+func (r *Reader) Skip(c Codec) (int, error) {
+	n, err := r.peekSize(c)
+	if err != nil {
+		return 0, err
+	}
+
+	r.off += n
+	return n, nil
+}
+
+// Seek moves the cursor to offset off.
+func (r *Reader) Seek(off int) error {
+	if off < 0 {
+		return r.errorf(ErrNegativeOffset, "seek to: %d", off)
+	}
+	if off > len(r.buf) {
+		return r.errorf(ErrShortBuffer, "seek to: %d, buffer size: %d", off, len(r.buf))
+	}
+	r.off = off
+	return nil
+}
+
+// Offset returns the current position of the cursor.
+func (r *Reader) Offset() int {
+	return r.off
+}
+
+// Remaining returns the number of bytes after the cursor.
+func (r *Reader) Remaining() int {
+	return len(r.buf) - r.off
+}
+
+// peekSize returns the size of the value at cursor, if the buffer is large
+// enough to contain it.
+func (r *Reader) peekSize(c Codec) (int, error) {
+	rest := r.buf[r.off:]
+
+	n, err := neededSizeChecked(c, rest)
+	if err != nil {
+		return 0, r.errorf(err, "size")
+	}
+	if n > len(rest) {
+		return 0, r.errorf(ErrShortBuffer, "need: %d, remaining: %d", n, len(rest))
+	}
+	return n, nil
+}
+
+// decodeRecovered is the same as decodeChecked except it returns an error
+// wrapping ErrCorrupted if Decode panics, e.g., on an out of range offset in
+// a malformed value.
+func decodeRecovered(c Codec, b []byte) (n int, v interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			n, v, err = 0, nil, errors.Wrapf(ErrCorrupted, "decode panic: %v", r)
+		}
+	}()
+
+	return decodeChecked(c, b)
+}
+
+func (r *Reader) errorf(err error, format string, args ...interface{}) error {
+	return &OffsetError{
+		Offset: r.off,
+		Err:    errors.Wrapf(err, format, args...),
+	}
+}
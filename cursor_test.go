@@ -0,0 +1,177 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder(t *testing.T) {
+
+	ta := require.New(t)
+
+	var b Builder
+	ta.Equal(0, b.Len())
+
+	b.Append(U16{}, uint16(0x0102)).
+		Append(String16{}, "ab").
+		Append(U8{}, uint8(3))
+
+	ta.Equal([]byte{2, 1, 0, 2, 'a', 'b', 3}, b.Bytes())
+	ta.Equal(7, b.Len())
+
+	b.Reset()
+	ta.Equal(0, b.Len())
+
+	nb := NewBuilder(10)
+	nb.Append(I32{}, int32(-1))
+	ta.Equal([]byte{0xff, 0xff, 0xff, 0xff}, nb.Bytes())
+}
+
+func TestReader(t *testing.T) {
+
+	ta := require.New(t)
+
+	xy, err := NewTypeCodec(typeXY{})
+	ta.NoError(err)
+
+	b := NewBuilder(0).
+		Append(U16{}, uint16(7)).
+		Append(String16{}, "hello").
+		Append(xy, typeXY{1, 2}).
+		Append(I8{}, int8(-3)).
+		Bytes()
+
+	r := NewReader(b)
+	ta.Equal(0, r.Offset())
+	ta.Equal(len(b), r.Remaining())
+
+	v, err := r.Read(U16{})
+	ta.NoError(err)
+	ta.Equal(uint16(7), v)
+	ta.Equal(2, r.Offset())
+
+	n, err := r.Skip(String16{})
+	ta.NoError(err)
+	ta.Equal(7, n)
+	ta.Equal(9, r.Offset())
+
+	v, err = r.Read(xy)
+	ta.NoError(err)
+	ta.Equal(typeXY{1, 2}, v)
+
+	v, err = r.Read(I8{})
+	ta.NoError(err)
+	ta.Equal(int8(-3), v)
+	ta.Equal(0, r.Remaining())
+
+	// read past the end
+
+	_, err = r.Read(U8{})
+	ta.Equal(ErrShortBuffer, errors.Cause(err))
+	oe, ok := err.(*OffsetError)
+	ta.True(ok)
+	ta.Equal(len(b), oe.Offset)
+
+	// seek back and read the string
+
+	ta.NoError(r.Seek(2))
+	v, err = r.Read(String16{})
+	ta.NoError(err)
+	ta.Equal("hello", v)
+
+	ta.Equal(ErrNegativeOffset, errors.Cause(r.Seek(-1)))
+	ta.Equal(ErrShortBuffer, errors.Cause(r.Seek(len(b)+1)))
+	ta.Equal(9, r.Offset())
+}
+
+func TestReaderShortBuffer(t *testing.T) {
+
+	ta := require.New(t)
+
+	cases := []struct {
+		input []byte
+		codec Codec
+	}{
+		{[]byte{}, U8{}},
+		{[]byte{1, 2, 3}, U32{}},
+		{[]byte{0}, String16{}},
+		{[]byte{0, 5, 'a'}, String16{}},
+		{[]byte{0, 5, 'a'}, noStreamSizer{String16{}}},
+		{[]byte{1, 2}, Bytes{size: 3}},
+	}
+
+	for i, c := range cases {
+		r := NewReader(c.input)
+
+		_, err := r.Read(c.codec)
+		ta.Equal(ErrShortBuffer, errors.Cause(err), "%d-th: case: %+v", i+1, c)
+		ta.Equal(0, r.Offset())
+
+		_, err = r.Skip(c.codec)
+		ta.Equal(ErrShortBuffer, errors.Cause(err), "%d-th: case: %+v", i+1, c)
+		ta.Equal(0, r.Offset())
+	}
+}
+
+func TestReaderSizeMismatch(t *testing.T) {
+
+	ta := require.New(t)
+
+	r := NewReader([]byte{1, 2})
+	_, err := r.Read(badSize{})
+	ta.Equal(ErrSizeMismatch, errors.Cause(err))
+	ta.Contains(err.Error(), "offset: 0")
+}
+
+func TestReaderCorruptedSize(t *testing.T) {
+
+	ta := require.New(t)
+
+	r := NewReader([]byte{1, 0xff})
+	_, err := r.Read(corruptSize{})
+	ta.NoError(err)
+
+	_, err = r.Read(corruptSize{})
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+	oe, ok := err.(*OffsetError)
+	ta.True(ok)
+	ta.Equal(1, oe.Offset)
+
+	_, err = r.Skip(corruptSize{})
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+	ta.Equal(1, r.Offset())
+}
+
+func TestReaderCorruptedPayload(t *testing.T) {
+
+	ta := require.New(t)
+
+	vals := make([]uint32, 300)
+	for i := range vals {
+		vals[i] = uint32(i * 7)
+	}
+	b := FrameOfRef{}.Encode(vals)
+
+	// offset of the 2nd block points out of the value
+	bad := append([]byte{}, b...)
+	binary.LittleEndian.PutUint32(bad[intBlocksHeaderSize+4:], 0xffff)
+
+	r := NewReader(append([]byte{1}, bad...))
+	_, err := r.Read(U8{})
+	ta.NoError(err)
+
+	_, err = r.Read(FrameOfRef{})
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+	oe, ok := err.(*OffsetError)
+	ta.True(ok)
+	ta.Equal(1, oe.Offset)
+	ta.Equal(1, r.Offset())
+
+	// the cursor stays and the value can be skipped
+	n, err := r.Skip(FrameOfRef{})
+	ta.NoError(err)
+	ta.Equal(len(b), n)
+}
@@ -0,0 +1,92 @@
+package qcodec
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ErrSizeMismatch indicates a Codec consumed a different number of bytes than
+// it reported with EncodedSize.
+var ErrSizeMismatch = errors.New("decoded size does not match encoded size")
+
+// Decoder decodes a stream of values that arrives in chunks.
+// It buffers an incomplete value until enough bytes are fed.
+//
+// It works with any Codec.
+// A Codec with a var-length encoding should implement StreamSizer so that an
+// incomplete header is recognized.
+// No goroutine or io.Reader is involved.
+type Decoder struct {
+	codec Codec
+	buf   []byte
+}
+
+// NewDecoder creates a *Decoder that decodes values with c.
+func NewDecoder(c Codec) *Decoder {
+	return &Decoder{codec: c}
+}
+
+// Feed appends a chunk of bytes and decodes as many complete values as
+// possible.
+//
+// It returns the decoded values and the number of additional bytes required
+// before the next value could be decoded, or before its size could be
+// determined.
+// A panic of the Codec on malformed data is returned as an error wrapping
+// ErrCorrupted.
+//
+// The values decoded by a Codec that does not copy, such as Bytes, refer to
+// the buffer of the Decoder, which holds a copy of the fed chunks.
+// Decoded bytes in the buffer are never overwritten, thus such values stay
+// valid after further Feed calls.
+//
+// A zero-size value, such as one by Dummy, consumes no byte: it is decoded
+// once per Feed, and bytes fed after it are kept in the buffer, since they are
+// never part of a value, until Reset.
+func (d *Decoder) Feed(chunk []byte) ([]interface{}, int, error) {
+
+	d.buf = append(d.buf, chunk...)
+
+	var vals []interface{}
+	for {
+		n, err := neededSizeChecked(d.codec, d.buf)
+		if err != nil {
+			return vals, 0, err
+		}
+		if n > len(d.buf) {
+			return vals, n - len(d.buf), nil
+		}
+
+		consumed, v, err := decodeRecovered(d.codec, d.buf[:n])
+		if err != nil {
+			return vals, 0, err
+		}
+		if consumed != n {
+			return vals, 0, errors.Wrapf(ErrSizeMismatch,
+				"encoded size: %d, consumed: %d", n, consumed)
+		}
+
+		vals = append(vals, v)
+		d.buf = d.buf[n:]
+
+		if len(d.buf) == 0 {
+			// Do not reuse the buffer: a decoded value may refer to it.
+			d.buf = nil
+		}
+
+		// A zero size value, such as one by Dummy, is decoded once per feed,
+		// otherwise it never ends.
+		if n == 0 {
+			return vals, 0, nil
+		}
+	}
+}
+
+// Buffered returns the number of bytes fed but not yet decoded.
+func (d *Decoder) Buffered() int {
+	return len(d.buf)
+}
+
+// Reset discards buffered bytes.
+func (d *Decoder) Reset() {
+	d.buf = nil
+}
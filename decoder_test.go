@@ -0,0 +1,226 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// noStreamSizer hides NeededSize of the embedded Codec.
+type noStreamSizer struct {
+	Codec
+}
+
+// badSize consumes 1 byte but reports 2.
+type badSize struct {
+	U8
+}
+
+func (c badSize) EncodedSize(b []byte) int {
+	return 2
+}
+
+// corruptSize reports a corrupted size header if the first byte is 0xff.
+type corruptSize struct {
+	U8
+}
+
+func (c corruptSize) NeededSize(b []byte) int {
+	n, err := c.NeededSizeChecked(b)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+func (c corruptSize) NeededSizeChecked(b []byte) (int, error) {
+	if len(b) > 0 && b[0] == 0xff {
+		return 0, errors.Wrapf(ErrCorrupted, "size header: %d", b[0])
+	}
+	return 1, nil
+}
+
+func TestString16NeededSize(t *testing.T) {
+
+	ta := require.New(t)
+
+	m := String16{}
+
+	ta.Equal(2, m.NeededSize(nil))
+	ta.Equal(2, m.NeededSize([]byte{0}))
+	ta.Equal(2, m.NeededSize([]byte{0, 0}))
+	ta.Equal(5, m.NeededSize([]byte{0, 3}))
+	ta.Equal(5, m.NeededSize([]byte{0, 3, 'a', 'b', 'c', 'd'}))
+}
+
+func TestDecoder(t *testing.T) {
+
+	ta := require.New(t)
+
+	xy, err := NewTypeCodec(typeXY{})
+	ta.NoError(err)
+
+	cases := []struct {
+		codec Codec
+		input []interface{}
+	}{
+		{U8{}, []interface{}{uint8(1), uint8(2), uint8(3)}},
+		{U32{}, []interface{}{uint32(1), uint32(0x01020304)}},
+		{I64{}, []interface{}{int64(-1), int64(5)}},
+		{Int{}, []interface{}{1, -2}},
+		{String16{}, []interface{}{"", "a", "", "abc", "foo bar"}},
+		{noStreamSizer{String16{}}, []interface{}{"", "a", "", "abc", "foo bar"}},
+		{Bytes{size: 3}, []interface{}{[]byte("abc"), []byte("xyz")}},
+		{xy, []interface{}{typeXY{1, 2}, typeXY{-1, 3}}},
+	}
+
+	for i, c := range cases {
+
+		var buf []byte
+		for _, v := range c.input {
+			buf = append(buf, c.codec.Encode(v)...)
+		}
+
+		// feed all at once
+
+		d := NewDecoder(c.codec)
+		vals, need, err := d.Feed(buf)
+		ta.NoError(err)
+		ta.Equal(c.input, vals, "%d-th: case: %+v", i+1, c)
+		ta.True(need > 0, "%d-th: case: %+v", i+1, c)
+		ta.Equal(0, d.Buffered())
+
+		// feed by chunks of every size
+
+		for step := 1; step <= len(buf); step++ {
+			d := NewDecoder(c.codec)
+			var got []interface{}
+
+			for off := 0; off < len(buf); off += step {
+				end := off + step
+				if end > len(buf) {
+					end = len(buf)
+				}
+
+				vals, need, err := d.Feed(buf[off:end])
+				ta.NoError(err)
+				ta.True(need > 0, "%d-th: step: %d", i+1, step)
+
+				got = append(got, vals...)
+			}
+			ta.Equal(c.input, got, "%d-th: step: %d", i+1, step)
+			ta.Equal(0, d.Buffered())
+		}
+	}
+}
+
+func TestDecoderNeed(t *testing.T) {
+
+	ta := require.New(t)
+
+	d := NewDecoder(String16{})
+
+	vals, need, err := d.Feed([]byte{0})
+	ta.NoError(err)
+	ta.Nil(vals)
+	ta.Equal(1, need)
+
+	vals, need, err = d.Feed([]byte{3, 'a'})
+	ta.NoError(err)
+	ta.Nil(vals)
+	ta.Equal(2, need)
+	ta.Equal(3, d.Buffered())
+
+	vals, need, err = d.Feed([]byte{'b', 'c', 0})
+	ta.NoError(err)
+	ta.Equal([]interface{}{"abc"}, vals)
+	ta.Equal(1, need)
+
+	d.Reset()
+	ta.Equal(0, d.Buffered())
+
+	vals, need, err = d.Feed(nil)
+	ta.NoError(err)
+	ta.Nil(vals)
+	ta.Equal(2, need)
+}
+
+func TestDecoderDummy(t *testing.T) {
+
+	ta := require.New(t)
+
+	d := NewDecoder(Dummy{})
+
+	vals, need, err := d.Feed([]byte{1, 2})
+	ta.NoError(err)
+	ta.Equal([]interface{}{nil}, vals)
+	ta.Equal(0, need)
+
+	// fed bytes are not consumed by zero-size values, until Reset
+	vals, _, err = d.Feed([]byte{3})
+	ta.NoError(err)
+	ta.Equal([]interface{}{nil}, vals)
+	ta.Equal(3, d.Buffered())
+
+	d.Reset()
+	ta.Equal(0, d.Buffered())
+
+	vals, _, err = d.Feed(nil)
+	ta.NoError(err)
+	ta.Equal([]interface{}{nil}, vals)
+}
+
+func TestDecoderSizeMismatch(t *testing.T) {
+
+	ta := require.New(t)
+
+	d := NewDecoder(badSize{})
+
+	vals, _, err := d.Feed([]byte{1, 2})
+	ta.Nil(vals)
+	ta.Equal(ErrSizeMismatch, errors.Cause(err))
+}
+
+func TestDecoderCorruptedSize(t *testing.T) {
+
+	ta := require.New(t)
+
+	d := NewDecoder(corruptSize{})
+
+	vals, _, err := d.Feed([]byte{1, 2, 0xff})
+	ta.Equal([]interface{}{uint8(1), uint8(2)}, vals)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+	ta.Equal(1, d.Buffered())
+}
+
+func TestDecoderCorruptedPayload(t *testing.T) {
+
+	ta := require.New(t)
+
+	vals := make([]uint32, 300)
+	b := FrameOfRef{}.Encode(vals)
+	binary.LittleEndian.PutUint32(b[intBlocksHeaderSize+4:], 0xffff)
+
+	d := NewDecoder(FrameOfRef{})
+	_, _, err := d.Feed(b)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+}
+
+func TestDecoderRefersToBuffer(t *testing.T) {
+
+	ta := require.New(t)
+
+	d := NewDecoder(Bytes{size: 2})
+
+	chunk := []byte{1, 2, 3}
+	vals, _, err := d.Feed(chunk)
+	ta.NoError(err)
+	chunk[0] = 9
+
+	// decoded values are not affected by changing the chunk or feeding more
+	_, _, err = d.Feed([]byte{4, 5, 6})
+	ta.NoError(err)
+	ta.Equal([]byte{1, 2}, vals[0])
+}
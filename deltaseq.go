@@ -0,0 +1,406 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DeltaMode specifies what DeltaSeq stores for every value.
+type DeltaMode uint8
+
+const (
+	// Delta stores the difference from the previous value.
+	Delta DeltaMode = iota
+	// DeltaOfDelta stores the difference between two successive deltas, as
+	// Gorilla does for timestamps.
+	// Values with a nearly constant step, such as periodic timestamps, are
+	// stored in about 1 byte each.
+	DeltaOfDelta
+)
+
+// defaultDeltaSeqInterval is the number of values between two checkpoints.
+const defaultDeltaSeqInterval = 64
+
+// deltaSeqHeaderSize is the size of element type, mode, interval and count.
+const deltaSeqHeaderSize = 10
+
+// deltaSeqCheckpointSize is the size of value, delta and data offset of a
+// checkpoint.
+const deltaSeqCheckpointSize = 20
+
+// element types of DeltaSeq.
+const (
+	seqUint64 byte = iota
+	seqInt64
+	seqTime
+)
+
+// seqZoneHeaderSize is the size of kind, offset and name length of the zone
+// of a time sequence.
+const seqZoneHeaderSize = 6
+
+// zone kinds of a time sequence.
+const (
+	seqZoneUTC byte = iota
+	seqZoneLocal
+	seqZoneFixed
+)
+
+// DeltaSeq is a Codec of sequences of []uint64, []int64 or []time.Time that
+// are sorted or nearly sorted, such as ids or timestamps.
+// Every value is stored as a zigzag varint of the difference from the
+// previous one, or of the difference between deltas in DeltaOfDelta mode.
+// Differences wrap around thus any sequence round-trips exactly, though
+// unsorted ones are not compact.
+//
+// Every "Interval"-th value is a checkpoint that stores the value itself, thus
+// Get and Slice decode at most Interval-1 values before the requested one.
+//
+// A time.Time is stored as nanoseconds since the Unix epoch.
+// Encode panics if a time.Time can not be represented this way, which is out
+// of years 1678 to 2262.
+// The Location of the first time is stored once for the sequence, and every
+// time is decoded in it: UTC, Local, or otherwise a fixed zone with the name
+// and offset of the first time.
+// Thus times in UTC, Local or a fixed zone round-trip exactly.
+// A Location with daylight saving rules, such as one by time.LoadLocation,
+// becomes a fixed zone; the instant is always kept.
+//
+// The encoded layout is, integers are little-endian:
+//
+//     type      uint8  // 0: uint64, 1: int64, 2: time.Time
+//     mode      uint8
+//     interval  uint32
+//     n         uint32 // number of values
+//     checkpoints: ceil(n/interval) times
+//       value   uint64
+//       delta   uint64 // from the previous value, 0 for the first
+//       offset  uint32 // offset in data of the next value
+//     size      uint32 // size of data
+//     data      varint * (n - ceil(n/interval))
+//     zone:              // time.Time only
+//       kind    uint8  // 0: UTC, 1: Local, 2: fixed
+//       offset  int32  // seconds east of UTC, 0 unless fixed
+//       nlen    uint8
+//       name    nlen bytes
+//
+// The zero value is a Delta mode codec with interval 64.
+type DeltaSeq struct {
+	// Mode specifies to store deltas or delta-of-deltas.
+	Mode DeltaMode
+	// Interval is the number of values between two checkpoints.
+	// 0 means 64.
+	Interval int
+}
+
+// Encode converts a []uint64, []int64 or []time.Time to bytes.
+// It panics if d is of other type, or Interval is negative.
+func (c DeltaSeq) Encode(d interface{}) []byte {
+	typ, vals := toSeqValues(d)
+
+	interval := c.interval()
+
+	ncp := (len(vals) + interval - 1) / interval
+	cps := make([]byte, 0, ncp*deltaSeqCheckpointSize)
+
+	var data []byte
+	var buf [binary.MaxVarintLen64]byte
+
+	var prev, prevDelta uint64
+	for i, v := range vals {
+		delta := v - prev
+		if i == 0 {
+			delta = 0
+		}
+
+		if i%interval == 0 {
+			cps = appendU64(cps, v)
+			cps = appendU64(cps, delta)
+			cps = appendU32(cps, len(data))
+		} else {
+			x := delta
+			if c.Mode == DeltaOfDelta {
+				x = delta - prevDelta
+			}
+			n := binary.PutVarint(buf[:], int64(x))
+			data = append(data, buf[:n]...)
+		}
+
+		prev, prevDelta = v, delta
+	}
+
+	rst := make([]byte, 0, deltaSeqHeaderSize+len(cps)+4+len(data))
+	rst = append(rst, typ, byte(c.Mode))
+	rst = appendU32(rst, interval)
+	rst = appendU32(rst, len(vals))
+	rst = append(rst, cps...)
+	rst = appendU32(rst, len(data))
+	rst = append(rst, data...)
+
+	if typ == seqTime {
+		rst = appendSeqZone(rst, d.([]time.Time))
+	}
+	return rst
+}
+
+// Decode converts bytes to a []uint64, []int64 or []time.Time, the same type
+// as encoded.
+// Mode and Interval are read from b thus any DeltaSeq decodes it.
+// It returns number bytes consumed and the slice.
+func (c DeltaSeq) Decode(b []byte) (int, interface{}) {
+	h := readDeltaSeqHeader(b)
+	return c.EncodedSize(b), h.slice(b, 0, h.n)
+}
+
+// Size returns the size in byte after encoding v.
+func (c DeltaSeq) Size(d interface{}) int {
+	return len(c.Encode(d))
+}
+
+// EncodedSize returns size of the encoded sequence.
+func (c DeltaSeq) EncodedSize(b []byte) int {
+	h := readDeltaSeqHeader(b)
+	end := h.dataEnd(b)
+	if h.typ == seqTime {
+		end += seqZoneHeaderSize + int(b[end+seqZoneHeaderSize-1])
+	}
+	return end
+}
+
+// NeededSize returns the encoded size if header, checkpoints and the zone
+// header of a time sequence are complete.
+func (c DeltaSeq) NeededSize(b []byte) int {
+	if len(b) < deltaSeqHeaderSize {
+		return deltaSeqHeaderSize
+	}
+	h := readDeltaSeqHeader(b)
+	if len(b) < h.dataStart {
+		return h.dataStart
+	}
+	if h.typ == seqTime {
+		if zoneEnd := h.dataEnd(b) + seqZoneHeaderSize; len(b) < zoneEnd {
+			return zoneEnd
+		}
+	}
+	return c.EncodedSize(b)
+}
+
+// Len returns the number of values in the encoded sequence.
+func (c DeltaSeq) Len(b []byte) int {
+	return readDeltaSeqHeader(b).n
+}
+
+// Get returns the i-th value, a uint64, int64 or time.Time.
+// It decodes from the nearest checkpoint before i.
+// It panics if i is out of range.
+func (c DeltaSeq) Get(b []byte, i int) interface{} {
+	h := readDeltaSeqHeader(b)
+	if i < 0 || i >= h.n {
+		panic(errors.Errorf("index out of range: %d, len: %d", i, h.n))
+	}
+	return fromSeqValue(h.typ, h.location(b), h.values(b, i, i+1)[0])
+}
+
+// Slice returns values in [from, to) in the same slice type as encoded.
+// It decodes from the nearest checkpoint before "from".
+// It panics if the range is invalid.
+func (c DeltaSeq) Slice(b []byte, from, to int) interface{} {
+	h := readDeltaSeqHeader(b)
+	if from < 0 || to > h.n || from > to {
+		panic(errors.Errorf("invalid range: [%d, %d), len: %d", from, to, h.n))
+	}
+	return h.slice(b, from, to)
+}
+
+func (c DeltaSeq) interval() int {
+	if c.Interval < 0 {
+		panic(errors.Wrapf(ErrInvalidInterval, "interval: %d", c.Interval))
+	}
+	if c.Interval == 0 {
+		return defaultDeltaSeqInterval
+	}
+	return c.Interval
+}
+
+type deltaSeqHeader struct {
+	typ       byte
+	mode      DeltaMode
+	interval  int
+	n         int
+	dataStart int
+}
+
+func readDeltaSeqHeader(b []byte) deltaSeqHeader {
+	h := deltaSeqHeader{
+		typ:      b[0],
+		mode:     DeltaMode(b[1]),
+		interval: int(binary.LittleEndian.Uint32(b[2:])),
+		n:        int(binary.LittleEndian.Uint32(b[6:])),
+	}
+	if h.interval <= 0 {
+		panic(errors.Wrapf(ErrMalformed, "interval: %d", h.interval))
+	}
+
+	ncp := (h.n + h.interval - 1) / h.interval
+	h.dataStart = deltaSeqHeaderSize + ncp*deltaSeqCheckpointSize + 4
+	return h
+}
+
+// dataEnd returns the end offset of data.
+func (h deltaSeqHeader) dataEnd(b []byte) int {
+	return h.dataStart + int(binary.LittleEndian.Uint32(b[h.dataStart-4:]))
+}
+
+// location returns the Location to decode times in, or nil if it is not a
+// time sequence.
+func (h deltaSeqHeader) location(b []byte) *time.Location {
+	if h.typ != seqTime {
+		return nil
+	}
+
+	p := b[h.dataEnd(b):]
+	switch p[0] {
+	case seqZoneUTC:
+		return time.UTC
+	case seqZoneLocal:
+		return time.Local
+	case seqZoneFixed:
+		offset := int(int32(binary.LittleEndian.Uint32(p[1:])))
+		name := string(p[seqZoneHeaderSize : seqZoneHeaderSize+int(p[5])])
+		return time.FixedZone(name, offset)
+	}
+	panic(errors.Wrapf(ErrMalformed, "zone kind: %d", p[0]))
+}
+
+// values decodes values in [from, to) as uint64.
+func (h deltaSeqHeader) values(b []byte, from, to int) []uint64 {
+
+	rst := make([]uint64, 0, to-from)
+
+	i := from - from%h.interval
+	data := b[h.dataStart:]
+
+	var v, delta uint64
+	p := 0
+
+	for ; i < to; i++ {
+		if i%h.interval == 0 {
+			cp := b[deltaSeqHeaderSize+i/h.interval*deltaSeqCheckpointSize:]
+			v = binary.LittleEndian.Uint64(cp)
+			delta = binary.LittleEndian.Uint64(cp[8:])
+			p = int(binary.LittleEndian.Uint32(cp[16:]))
+		} else {
+			x, n := binary.Varint(data[p:])
+			if n <= 0 {
+				panic(errors.Wrapf(ErrMalformed, "invalid varint at: %d", p))
+			}
+			p += n
+
+			if h.mode == DeltaOfDelta {
+				delta += uint64(x)
+			} else {
+				delta = uint64(x)
+			}
+			v += delta
+		}
+
+		if i >= from {
+			rst = append(rst, v)
+		}
+	}
+	return rst
+}
+
+func (h deltaSeqHeader) slice(b []byte, from, to int) interface{} {
+	vals := h.values(b, from, to)
+
+	switch h.typ {
+	case seqUint64:
+		return vals
+	case seqInt64:
+		rst := make([]int64, len(vals))
+		for i, v := range vals {
+			rst[i] = int64(v)
+		}
+		return rst
+	case seqTime:
+		loc := h.location(b)
+		rst := make([]time.Time, len(vals))
+		for i, v := range vals {
+			rst[i] = fromSeqValue(seqTime, loc, v).(time.Time)
+		}
+		return rst
+	}
+	panic(errors.Wrapf(ErrMalformed, "element type: %d", h.typ))
+}
+
+// toSeqValues converts a sequence to uint64 values.
+func toSeqValues(d interface{}) (byte, []uint64) {
+	switch s := d.(type) {
+	case []uint64:
+		return seqUint64, s
+	case []int64:
+		rst := make([]uint64, len(s))
+		for i, v := range s {
+			rst[i] = uint64(v)
+		}
+		return seqInt64, rst
+	case []time.Time:
+		rst := make([]uint64, len(s))
+		for i, t := range s {
+			ns := t.UnixNano()
+			if !time.Unix(0, ns).Equal(t) {
+				panic(errors.Errorf("time out of range of int64 nanoseconds: %v", t))
+			}
+			rst[i] = uint64(ns)
+		}
+		return seqTime, rst
+	}
+	panic(errors.Wrapf(ErrUnknownEltType, "type: %T", d))
+}
+
+// fromSeqValue converts a uint64 value to the element type, a time.Time is in
+// loc.
+func fromSeqValue(typ byte, loc *time.Location, v uint64) interface{} {
+	switch typ {
+	case seqUint64:
+		return v
+	case seqInt64:
+		return int64(v)
+	case seqTime:
+		t := time.Unix(0, int64(v))
+		if loc == time.UTC {
+			// UTC() instead of In(time.UTC) keeps the time the same as one
+			// built with time.UTC.
+			return t.UTC()
+		}
+		return t.In(loc)
+	}
+	panic(errors.Wrapf(ErrMalformed, "element type: %d", typ))
+}
+
+// appendSeqZone appends the Location of the first time, UTC if ts is empty.
+func appendSeqZone(b []byte, ts []time.Time) []byte {
+	loc := time.UTC
+	if len(ts) > 0 {
+		loc = ts[0].Location()
+	}
+
+	switch loc {
+	case time.UTC:
+		return append(b, seqZoneUTC, 0, 0, 0, 0, 0)
+	case time.Local:
+		return append(b, seqZoneLocal, 0, 0, 0, 0, 0)
+	}
+
+	name, offset := ts[0].Zone()
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	b = append(b, seqZoneFixed)
+	b = appendU32(b, offset)
+	b = append(b, byte(len(name)))
+	return append(b, name...)
+}
@@ -0,0 +1,187 @@
+package qcodec
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeltaSeq(t *testing.T) {
+
+	ta := require.New(t)
+
+	ids := make([]uint64, 200)
+	for i := range ids {
+		ids[i] = 1000000 + uint64(i*i)
+	}
+
+	cases := []interface{}{
+		[]uint64{},
+		[]uint64{5},
+		ids,
+		[]uint64{math.MaxUint64, 0, math.MaxUint64, 1},
+		[]int64{},
+		[]int64{-3, 5, math.MinInt64, math.MaxInt64, 0},
+		[]time.Time{},
+		[]time.Time{
+			time.Unix(1600000000, 1).UTC(),
+			time.Unix(1600000010, 2).UTC(),
+			time.Unix(0, math.MinInt64).UTC(),
+		},
+	}
+
+	codecs := []DeltaSeq{
+		{},
+		{Mode: DeltaOfDelta},
+		{Interval: 1},
+		{Mode: DeltaOfDelta, Interval: 3},
+	}
+
+	for _, c := range codecs {
+		for i, input := range cases {
+			b := c.Encode(input)
+			ta.Equal(len(b), c.Size(input), "%d-th: codec: %+v", i+1, c)
+			ta.Equal(len(b), c.EncodedSize(b))
+			ta.Equal(len(b), c.NeededSize(b))
+
+			n, v := c.Decode(append(b, 1, 2))
+			ta.Equal(len(b), n)
+			ta.Equal(input, v, "%d-th: codec: %+v", i+1, c)
+
+			// decodable by any DeltaSeq
+			_, v = DeltaSeq{}.Decode(b)
+			ta.Equal(input, v)
+		}
+	}
+}
+
+func TestDeltaSeqGet(t *testing.T) {
+
+	ta := require.New(t)
+
+	vals := make([]int64, 100)
+	for i := range vals {
+		vals[i] = int64(i*3) - 50
+	}
+
+	for _, c := range []DeltaSeq{{Interval: 7}, {Mode: DeltaOfDelta, Interval: 10}} {
+		b := c.Encode(vals)
+		ta.Equal(100, c.Len(b))
+
+		for i, v := range vals {
+			ta.Equal(v, c.Get(b, i))
+		}
+
+		ta.Equal(vals[13:58], c.Slice(b, 13, 58))
+		ta.Equal(vals[:0], c.Slice(b, 0, 0))
+		ta.Equal(vals, c.Slice(b, 0, 100))
+
+		testPanic(t, func() { c.Get(b, 100) }, "index out of range")
+		testPanic(t, func() { c.Get(b, -1) }, "negative index")
+		testPanic(t, func() { c.Slice(b, 5, 4) }, "invalid range")
+		testPanic(t, func() { c.Slice(b, 0, 101) }, "invalid range")
+	}
+}
+
+func TestDeltaSeqTime(t *testing.T) {
+
+	ta := require.New(t)
+
+	loc := time.FixedZone("X", 3600)
+	start := time.Date(2020, 1, 2, 3, 4, 5, 6, loc)
+
+	ts := make([]time.Time, 1000)
+	for i := range ts {
+		// periodic with jitter
+		ts[i] = start.Add(time.Duration(i)*time.Second + time.Duration(i%3))
+	}
+
+	dod := DeltaSeq{Mode: DeltaOfDelta}
+	b := dod.Encode(ts)
+
+	// zone is kept
+	_, v := dod.Decode(b)
+	ta.Equal(ts, v)
+	ta.Equal(ts[500], dod.Get(b, 500))
+	ta.Equal("2020-01-02T03:04:05+01:00", v.([]time.Time)[0].Format(time.RFC3339))
+
+	// delta-of-delta is more compact for periodic values
+	ta.True(len(b) < len(DeltaSeq{}.Encode(ts)))
+	ta.True(len(b) < 2*len(ts), "size: %d", len(b))
+
+	// monotonic clock reading is dropped
+	now := time.Now()
+	_, v = dod.Decode(dod.Encode([]time.Time{now}))
+	ta.True(now.Equal(v.([]time.Time)[0]))
+
+	testPanic(t, func() { dod.Encode([]time.Time{time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC)}) }, "out of range")
+}
+
+func TestDeltaSeqTimeZone(t *testing.T) {
+
+	ta := require.New(t)
+
+	c := DeltaSeq{}
+	start := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	for _, loc := range []*time.Location{time.UTC, time.Local, time.FixedZone("", -7200)} {
+		ts := []time.Time{start.In(loc), start.Add(time.Hour).In(loc)}
+		b := c.Encode(ts)
+		ta.Equal(len(b), c.EncodedSize(b))
+		ta.Equal(len(b), c.NeededSize(b))
+
+		_, v := c.Decode(b)
+		ta.Equal(ts, v, "loc: %v", loc)
+		ta.Equal(ts[1], c.Get(b, 1))
+	}
+
+	// times in other zones are decoded in the zone of the first, at the same
+	// instant
+	ts := []time.Time{start.In(time.FixedZone("A", 3600)), start.In(time.FixedZone("B", -3600))}
+	_, v := c.Decode(c.Encode(ts))
+	got := v.([]time.Time)
+	ta.True(ts[1].Equal(got[1]))
+	name, offset := got[1].Zone()
+	ta.Equal("A", name)
+	ta.Equal(3600, offset)
+
+	// zone header comes after data
+	b := c.Encode([]time.Time{start.In(time.FixedZone("ABC", 60))})
+	dataEnd := len(b) - seqZoneHeaderSize - 3
+	ta.Equal(dataEnd+seqZoneHeaderSize, c.NeededSize(b[:dataEnd]))
+	ta.Equal(len(b), c.NeededSize(b[:dataEnd+seqZoneHeaderSize]))
+
+	bad := append([]byte{}, b...)
+	bad[dataEnd] = 9
+	testPanic(t, func() { c.Decode(bad) }, "malformed zone")
+}
+
+func TestDeltaSeqError(t *testing.T) {
+
+	ta := require.New(t)
+
+	c := DeltaSeq{}
+
+	testPanic(t, func() { c.Encode([]uint32{1}) }, "unsupported type")
+	testPanic(t, func() { DeltaSeq{Interval: -1}.Encode([]uint64{1}) }, "negative interval")
+
+	func() {
+		defer func() {
+			ta.Equal(ErrUnknownEltType, errors.Cause(recover().(error)))
+		}()
+		c.Encode([]int{1})
+	}()
+
+	b := c.Encode([]uint64{1, 2, 3})
+	ta.Equal(deltaSeqHeaderSize, c.NeededSize(b[:3]))
+	ta.Equal(deltaSeqHeaderSize+deltaSeqCheckpointSize+4, c.NeededSize(b[:deltaSeqHeaderSize]))
+	ta.Equal(len(b), c.NeededSize(b[:deltaSeqHeaderSize+deltaSeqCheckpointSize+4]))
+
+	// zero interval
+	bad := append([]byte{}, b...)
+	copy(bad[2:], []byte{0, 0, 0, 0})
+	testPanic(t, func() { c.Decode(bad) }, "malformed interval")
+}
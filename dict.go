@@ -0,0 +1,262 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Dict is a Codec of []string for columns of few distinct values, such as
+// country or status.
+// Distinct strings are stored once in a sorted dictionary, and every string is
+// stored as a code, its index in the dictionary.
+// Get, Value and CodeOf work on the encoded bytes without decoding.
+//
+// Codes are stored in 1, 2 or 4 bytes, the narrowest that fits, or bit-packed
+// at the width of the largest code if BitPacked is true.
+//
+// The encoded layout is, integers are little-endian:
+//
+//     ndict    uint32                   // number of distinct strings
+//     ends     uint32 * ndict           // end offset of every string
+//     strings  bytes                    // sorted distinct strings
+//     n        uint32                   // number of codes
+//     packed   uint8                    // 1 if codes are bit-packed
+//     width    uint8                    // width of a code in byte, or in bit
+//     codes    ceil(n*width/8) or n*width bytes
+type Dict struct {
+	// BitPacked specifies to pack codes in bits instead of bytes.
+	BitPacked bool
+}
+
+// Encode converts a []string to bytes.
+func (c Dict) Encode(d interface{}) []byte {
+	strs := d.([]string)
+
+	dict := append([]string{}, strs...)
+	sort.Strings(dict)
+	uniq := dict[:0]
+	for i, s := range dict {
+		if i == 0 || dict[i-1] != s {
+			uniq = append(uniq, s)
+		}
+	}
+	dict = uniq
+
+	rst := appendU32(nil, len(dict))
+	end := 0
+	for _, s := range dict {
+		end += len(s)
+		rst = appendU32(rst, end)
+	}
+	for _, s := range dict {
+		rst = append(rst, s...)
+	}
+
+	codes := make([]uint64, len(strs))
+	for i, s := range strs {
+		codes[i] = uint64(sort.SearchStrings(dict, s))
+	}
+
+	packed, width := c.codeWidth(len(dict))
+
+	rst = appendU32(rst, len(strs))
+	if packed {
+		rst = append(rst, 1, byte(width))
+		return packBits(rst, codes, width)
+	}
+
+	rst = append(rst, 0, byte(width))
+	for _, code := range codes {
+		switch width {
+		case 1:
+			rst = append(rst, byte(code))
+		case 2:
+			rst = appendU16(rst, uint16(code))
+		default:
+			rst = appendU32(rst, int(code))
+		}
+	}
+	return rst
+}
+
+// Decode converts bytes to a []string.
+// It returns number bytes consumed and the slice.
+func (c Dict) Decode(b []byte) (int, interface{}) {
+	dv := readDict(b)
+
+	strs := make([]string, dv.n)
+	for i := range strs {
+		strs[i] = dv.value(dv.checkedCode(i))
+	}
+	return dv.size, strs
+}
+
+// Size returns the size in byte after encoding v.
+func (c Dict) Size(d interface{}) int {
+	return len(c.Encode(d))
+}
+
+// EncodedSize returns size of the encoded column.
+func (c Dict) EncodedSize(b []byte) int {
+	return readDict(b).size
+}
+
+// NeededSize returns the encoded size if the dictionary and header of codes
+// are complete.
+func (c Dict) NeededSize(b []byte) int {
+	if len(b) < 4 {
+		return 4
+	}
+	ndict := int(binary.LittleEndian.Uint32(b))
+	strStart := 4 + 4*ndict
+	if len(b) < strStart {
+		return strStart
+	}
+
+	codesStart := strStart + 6
+	if ndict > 0 {
+		codesStart += int(binary.LittleEndian.Uint32(b[strStart-4:]))
+	}
+	if len(b) < codesStart {
+		return codesStart
+	}
+	return c.EncodedSize(b)
+}
+
+// Len returns the number of strings.
+func (c Dict) Len(b []byte) int {
+	return readDict(b).n
+}
+
+// Get returns the i-th string.
+// It panics if i is out of range.
+func (c Dict) Get(b []byte, i int) string {
+	dv := readDict(b)
+	return dv.value(dv.checkedCode(i))
+}
+
+// Code returns the code of the i-th string.
+// It panics if i is out of range.
+func (c Dict) Code(b []byte, i int) int {
+	return readDict(b).checkedCode(i)
+}
+
+// DictLen returns the number of distinct strings.
+func (c Dict) DictLen(b []byte) int {
+	return readDict(b).ndict
+}
+
+// Value returns the string of a code.
+// It panics if code is out of range.
+func (c Dict) Value(b []byte, code int) string {
+	dv := readDict(b)
+	if code < 0 || code >= dv.ndict {
+		panic(errors.Errorf("code out of range: %d, dict len: %d", code, dv.ndict))
+	}
+	return dv.value(code)
+}
+
+// CodeOf returns the code of s and true, or false if s is not in the
+// dictionary.
+func (c Dict) CodeOf(b []byte, s string) (int, bool) {
+	dv := readDict(b)
+	code := sort.Search(dv.ndict, func(i int) bool { return dv.value(i) >= s })
+	return code, code < dv.ndict && dv.value(code) == s
+}
+
+// codeWidth returns if codes are bit-packed and the width of a code.
+func (c Dict) codeWidth(ndict int) (bool, uint) {
+	maxCode := uint64(0)
+	if ndict > 0 {
+		maxCode = uint64(ndict - 1)
+	}
+
+	if c.BitPacked {
+		return true, bitWidth(maxCode)
+	}
+
+	switch {
+	case maxCode <= 0xff:
+		return false, 1
+	case maxCode <= 0xffff:
+		return false, 2
+	}
+	return false, 4
+}
+
+// dictView is a view of an encoded Dict.
+type dictView struct {
+	ndict  int
+	ends   []byte
+	strs   []byte
+	n      int
+	packed bool
+	width  uint
+	codes  []byte
+	size   int
+}
+
+func readDict(b []byte) *dictView {
+	dv := &dictView{ndict: int(binary.LittleEndian.Uint32(b))}
+
+	strStart := 4 + 4*dv.ndict
+	dv.ends = b[4:strStart]
+
+	strEnd := strStart
+	if dv.ndict > 0 {
+		strEnd += int(binary.LittleEndian.Uint32(b[strStart-4:]))
+	}
+	dv.strs = b[strStart:strEnd]
+
+	p := b[strEnd:]
+	dv.n = int(binary.LittleEndian.Uint32(p))
+	dv.packed = p[4] == 1
+	dv.width = uint(p[5])
+
+	codesSize := dv.n * int(dv.width)
+	if dv.packed {
+		codesSize = packedSize(dv.n, dv.width)
+	} else if dv.width != 1 && dv.width != 2 && dv.width != 4 {
+		panic(errors.Wrapf(ErrMalformed, "code width: %d", dv.width))
+	}
+
+	dv.size = strEnd + 6 + codesSize
+	dv.codes = b[strEnd+6 : dv.size]
+	return dv
+}
+
+func (dv *dictView) value(code int) string {
+	start := 0
+	if code > 0 {
+		start = int(binary.LittleEndian.Uint32(dv.ends[(code-1)*4:]))
+	}
+	end := int(binary.LittleEndian.Uint32(dv.ends[code*4:]))
+	return string(dv.strs[start:end])
+}
+
+func (dv *dictView) code(i int) int {
+	if dv.packed {
+		return int(unpackBits(dv.codes, i, dv.width))
+	}
+
+	switch dv.width {
+	case 1:
+		return int(dv.codes[i])
+	case 2:
+		return int(binary.LittleEndian.Uint16(dv.codes[i*2:]))
+	}
+	return int(binary.LittleEndian.Uint32(dv.codes[i*4:]))
+}
+
+func (dv *dictView) checkedCode(i int) int {
+	if i < 0 || i >= dv.n {
+		panic(errors.Errorf("index out of range: %d, len: %d", i, dv.n))
+	}
+	code := dv.code(i)
+	if code >= dv.ndict {
+		panic(errors.Wrapf(ErrMalformed, "code: %d, dict len: %d", code, dv.ndict))
+	}
+	return code
+}
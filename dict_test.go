@@ -0,0 +1,98 @@
+package qcodec
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDict(t *testing.T) {
+
+	ta := require.New(t)
+
+	status := make([]string, 1000)
+	for i := range status {
+		status[i] = []string{"active", "deleted", "pending"}[i%3]
+	}
+
+	many := make([]string, 300)
+	for i := range many {
+		many[i] = fmt.Sprintf("v%d", i%260)
+	}
+
+	cases := [][]string{
+		{},
+		{""},
+		{"b", "a", "b", "", "a"},
+		status,
+		many,
+	}
+
+	for _, c := range []Dict{{}, {BitPacked: true}} {
+		for i, input := range cases {
+			b := c.Encode(input)
+			ta.Equal(len(b), c.Size(input), "%d-th: codec: %+v", i+1, c)
+			ta.Equal(len(b), c.EncodedSize(b))
+			ta.Equal(len(b), c.NeededSize(b))
+
+			n, v := c.Decode(append(b, 1))
+			ta.Equal(len(b), n)
+			ta.Equal(input, v)
+
+			ta.Equal(len(input), c.Len(b))
+			for j, s := range input {
+				ta.Equal(s, c.Get(b, j))
+
+				code, ok := c.CodeOf(b, s)
+				ta.True(ok)
+				ta.Equal(code, c.Code(b, j))
+				ta.Equal(s, c.Value(b, code))
+			}
+			testPanic(t, func() { c.Get(b, len(input)) }, "out of range")
+			testPanic(t, func() { c.Value(b, c.DictLen(b)) }, "code out of range")
+		}
+	}
+}
+
+func TestDictCodes(t *testing.T) {
+
+	ta := require.New(t)
+
+	c := Dict{}
+
+	b := c.Encode([]string{"x", "b", "x", "a"})
+	ta.Equal(3, c.DictLen(b))
+	ta.Equal([]int{2, 1, 2, 0}, []int{c.Code(b, 0), c.Code(b, 1), c.Code(b, 2), c.Code(b, 3)})
+
+	code, ok := c.CodeOf(b, "c")
+	ta.False(ok)
+	ta.Equal(2, code)
+	_, ok = c.CodeOf(b, "")
+	ta.False(ok)
+
+	// 3 strings of 1 byte, 4 one-byte codes
+	ta.Equal(4+3*4+3+6+4, len(b))
+
+	// 3 codes fit in 2 bits
+	bp := Dict{BitPacked: true}.Encode([]string{"x", "b", "x", "a"})
+	ta.Equal(4+3*4+3+6+1, len(bp))
+
+	// 300 distinct: 2-byte codes
+	many := make([]string, 300)
+	for i := range many {
+		many[i] = fmt.Sprintf("%03d", i)
+	}
+	b = c.Encode(many)
+	ta.Equal(4+300*4+900+6+600, len(b))
+
+	// a single distinct string takes 0 bit per code
+	bp = Dict{BitPacked: true}.Encode([]string{"a", "a", "a"})
+	ta.Equal(4+4+1+6, len(bp))
+	_, v := c.Decode(bp)
+	ta.Equal([]string{"a", "a", "a"}, v)
+
+	ta.Equal(4, c.NeededSize(nil))
+	ta.Equal(4+4, c.NeededSize(bp[:4]))
+	ta.Equal(4+4+1+6, c.NeededSize(bp[:4+4]))
+}
@@ -0,0 +1,234 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotSorted indicates values are required to be in ascending order but are
+// not.
+var ErrNotSorted = errors.New("values are not sorted")
+
+// eliasFanoHeaderSize is the size of the count and the low bits width.
+const eliasFanoHeaderSize = 5
+
+// EliasFano is a Codec of non-decreasing []uint64 in Elias-Fano encoding,
+// which takes about 2 + log2(max/n) bits per value.
+// Get, NextGEQ and Range work on the encoded bytes without decoding.
+//
+// Every value is split into the lower l bits, where l is about log2(max/n),
+// and the higher bits.
+// Lower bits are packed at width l.
+// Higher bits are stored in unary in a Bitmap: value i sets the bit at
+// i + (v >> l), thus Get(i) is a Select of the i-th one.
+//
+// The encoded layout is, integers are little-endian:
+//
+//     n      uint32 // number of values
+//     l      uint8  // width of lower bits
+//     lows   ceil(n*l/8) bytes
+//     highs  Bitmap of n + (max >> l) + 1 bits, or 0 bits if n is 0
+type EliasFano struct{}
+
+// Encode converts a non-decreasing []uint64 to bytes.
+// It panics with an error wrapping ErrNotSorted if d is not sorted.
+func (c EliasFano) Encode(d interface{}) []byte {
+	vals := d.([]uint64)
+	checkSorted(vals)
+	l, nbits := eliasFanoParams(vals)
+
+	lows := make([]uint64, len(vals))
+	words := make([]uint64, (nbits+63)/64)
+	for i, v := range vals {
+		if l > 0 {
+			lows[i] = v & (1<<l - 1)
+		}
+		pos := uint64(i) + v>>l
+		words[pos/64] |= 1 << (pos % 64)
+	}
+
+	rst := make([]byte, 0, eliasFanoSize(len(vals), l, nbits))
+	rst = appendU32(rst, len(vals))
+	rst = append(rst, byte(l))
+	rst = packBits(rst, lows, l)
+	return appendBitmap(rst, words, nbits)
+}
+
+// Decode converts bytes to a []uint64.
+// It returns number bytes consumed and the slice.
+func (c EliasFano) Decode(b []byte) (int, interface{}) {
+	ef := readEliasFano(b)
+
+	vals := make([]uint64, 0, ef.n)
+	ef.rangeFrom(0, func(i int, v uint64) bool {
+		vals = append(vals, v)
+		return true
+	})
+	return ef.size, vals
+}
+
+// Size returns the exact size in byte after encoding v.
+// It panics with an error wrapping ErrNotSorted if d is not sorted.
+func (c EliasFano) Size(d interface{}) int {
+	vals := d.([]uint64)
+	checkSorted(vals)
+	l, nbits := eliasFanoParams(vals)
+	return eliasFanoSize(len(vals), l, nbits)
+}
+
+// EncodedSize returns size of the encoded sequence.
+func (c EliasFano) EncodedSize(b []byte) int {
+	return readEliasFano(b).size
+}
+
+// NeededSize returns the encoded size if the headers are complete.
+func (c EliasFano) NeededSize(b []byte) int {
+	if len(b) < eliasFanoHeaderSize {
+		return eliasFanoHeaderSize
+	}
+
+	n := int(binary.LittleEndian.Uint32(b))
+	highStart := eliasFanoHeaderSize + packedSize(n, uint(b[4]))
+	if len(b) < highStart+bitmapHeaderSize {
+		return highStart + bitmapHeaderSize
+	}
+	return c.EncodedSize(b)
+}
+
+// Len returns the number of values.
+func (c EliasFano) Len(b []byte) int {
+	return int(binary.LittleEndian.Uint32(b))
+}
+
+// Get returns the i-th value.
+// It panics if i is out of range.
+func (c EliasFano) Get(b []byte, i int) uint64 {
+	ef := readEliasFano(b)
+	if i < 0 || i >= ef.n {
+		panic(errors.Errorf("index out of range: %d, len: %d", i, ef.n))
+	}
+	return ef.get(i)
+}
+
+// NextGEQ returns the index and the value of the first value that is greater
+// than or equal to x.
+// It returns false if there is no such value.
+func (c EliasFano) NextGEQ(b []byte, x uint64) (int, uint64, bool) {
+	ef := readEliasFano(b)
+	if ef.n == 0 {
+		return ef.n, 0, false
+	}
+
+	// Bucket h of higher bits is terminated by the h-th zero, thus the number
+	// of values with higher bits less than that of x is the number of ones
+	// before the first position after hx zeros.
+	hx := x >> ef.l
+	i := ef.n
+	nzeros := uint64(ef.highs.n - ef.n)
+	if hx < nzeros {
+		pos := sort.Search(ef.highs.n, func(p int) bool {
+			return uint64(p-ef.highs.rank(p)) >= hx
+		})
+		i = ef.highs.rank(pos)
+	}
+
+	idx, val, found := ef.n, uint64(0), false
+	ef.rangeFrom(i, func(j int, v uint64) bool {
+		if v >= x {
+			idx, val, found = j, v, true
+			return false
+		}
+		return true
+	})
+	return idx, val, found
+}
+
+// Range calls f with every value in order, until f returns false.
+func (c EliasFano) Range(b []byte, f func(i int, v uint64) bool) {
+	readEliasFano(b).rangeFrom(0, f)
+}
+
+// eliasFanoParams returns the width of lower bits and the number of bits of
+// higher bits.
+func eliasFanoParams(vals []uint64) (uint, int) {
+	n := len(vals)
+	if n == 0 {
+		return 0, 0
+	}
+
+	max := vals[n-1]
+	l := uint(0)
+	if max/uint64(n) > 0 {
+		l = uint(bits.Len64(max/uint64(n))) - 1
+	}
+	return l, n + int(max>>l) + 1
+}
+
+func eliasFanoSize(n int, l uint, nbits int) int {
+	return eliasFanoHeaderSize + packedSize(n, l) + bitmapSize(nbits)
+}
+
+// eliasFano is a view of an encoded EliasFano sequence.
+type eliasFano struct {
+	n     int
+	l     uint
+	lows  []byte
+	highs *bitmap
+	size  int
+}
+
+func readEliasFano(b []byte) *eliasFano {
+	ef := &eliasFano{
+		n: int(binary.LittleEndian.Uint32(b)),
+		l: uint(b[4]),
+	}
+	highStart := eliasFanoHeaderSize + packedSize(ef.n, ef.l)
+	ef.lows = b[eliasFanoHeaderSize:highStart]
+	ef.highs = readBitmap(b[highStart:])
+	ef.size = highStart + ef.highs.size
+	return ef
+}
+
+func (ef *eliasFano) get(i int) uint64 {
+	high := uint64(ef.highs.sel(i) - i)
+	return high<<ef.l | unpackBits(ef.lows, i, ef.l)
+}
+
+// rangeFrom calls f with values from the i-th, until f returns false.
+func (ef *eliasFano) rangeFrom(i int, f func(i int, v uint64) bool) {
+	if i >= ef.n {
+		return
+	}
+
+	pos := ef.highs.sel(i)
+	wi := pos / 64
+	w := ef.highs.word(wi) &^ (1<<uint(pos%64) - 1)
+
+	for i < ef.n {
+		for w == 0 {
+			wi++
+			w = ef.highs.word(wi)
+		}
+		pos = wi*64 + bits.TrailingZeros64(w)
+		w &= w - 1
+
+		v := uint64(pos-i)<<ef.l | unpackBits(ef.lows, i, ef.l)
+		if !f(i, v) {
+			return
+		}
+		i++
+	}
+}
+
+// checkSorted panics with an error wrapping ErrNotSorted if vals is not
+// non-decreasing.
+func checkSorted(vals []uint64) {
+	for i := 1; i < len(vals); i++ {
+		if vals[i] < vals[i-1] {
+			panic(errors.Wrapf(ErrNotSorted, "at: %d, %d < %d", i, vals[i], vals[i-1]))
+		}
+	}
+}
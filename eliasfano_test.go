@@ -0,0 +1,148 @@
+package qcodec
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEliasFano(t *testing.T) {
+
+	ta := require.New(t)
+
+	rnd := rand.New(rand.NewSource(1))
+
+	random := make([]uint64, 5000)
+	for i := range random {
+		random[i] = uint64(rnd.Int63n(1 << 30))
+	}
+	sort.Slice(random, func(i, j int) bool { return random[i] < random[j] })
+
+	dups := []uint64{0, 0, 0, 7, 7, 8, 1000, 1000}
+
+	cases := [][]uint64{
+		{},
+		{0},
+		{5},
+		{math.MaxUint64},
+		{0, math.MaxUint64},
+		{1, 2, 3, 4, 5, 6, 7, 8},
+		dups,
+		random,
+	}
+
+	c := EliasFano{}
+
+	for i, input := range cases {
+		b := c.Encode(input)
+		ta.Equal(len(b), c.Size(input), "%d-th: len: %d", i+1, len(input))
+		ta.Equal(len(b), c.EncodedSize(b))
+		ta.Equal(len(b), c.NeededSize(b))
+
+		n, v := c.Decode(append(b, 1))
+		ta.Equal(len(b), n)
+		ta.Equal(input, v)
+		ta.Equal(len(input), c.Len(b))
+
+		for j, x := range input {
+			ta.Equal(x, c.Get(b, j))
+		}
+
+		var got []uint64
+		c.Range(b, func(j int, x uint64) bool {
+			ta.Equal(len(got), j)
+			got = append(got, x)
+			return true
+		})
+		ta.Equal(len(input), len(got))
+
+		testPanic(t, func() { c.Get(b, len(input)) }, "out of range")
+	}
+
+	// about 2 + log2(2^30/5000) bits per value
+	b := c.Encode(random)
+	ta.True(len(b)*8 < len(random)*(2+18+1), "size: %d", len(b))
+}
+
+func TestEliasFanoNextGEQ(t *testing.T) {
+
+	ta := require.New(t)
+
+	rnd := rand.New(rand.NewSource(2))
+
+	random := make([]uint64, 3000)
+	for i := range random {
+		random[i] = uint64(rnd.Int63n(100000))
+	}
+	sort.Slice(random, func(i, j int) bool { return random[i] < random[j] })
+
+	cases := [][]uint64{
+		{},
+		{5},
+		{0, 0, 3, 3, 3, 9, 1 << 40},
+		random,
+	}
+
+	c := EliasFano{}
+
+	for _, input := range cases {
+		b := c.Encode(input)
+
+		probes := []uint64{0, 1, 2, 3, 4, 8, 9, 10, 1 << 40, 1<<40 + 1, math.MaxUint64}
+		for k := 0; k < 500; k++ {
+			probes = append(probes, uint64(rnd.Int63n(110000)))
+		}
+
+		for _, x := range probes {
+			want := sort.Search(len(input), func(i int) bool { return input[i] >= x })
+
+			idx, v, ok := c.NextGEQ(b, x)
+			if want == len(input) {
+				ta.False(ok, "x: %d", x)
+				continue
+			}
+			ta.True(ok, "x: %d", x)
+			ta.Equal(want, idx, "x: %d", x)
+			ta.Equal(input[want], v)
+		}
+	}
+}
+
+func TestEliasFanoError(t *testing.T) {
+
+	ta := require.New(t)
+
+	c := EliasFano{}
+
+	for _, input := range [][]uint64{{1, 3, 2}, {1000, 5}} {
+		for _, f := range []func(){
+			func() { c.Encode(input) },
+			func() { c.Size(input) },
+		} {
+			func() {
+				defer func() {
+					ta.Equal(ErrNotSorted, errors.Cause(recover().(error)), "input: %v", input)
+				}()
+				f()
+			}()
+		}
+	}
+
+	b := c.Encode([]uint64{1, 3, 100})
+	ta.Equal(eliasFanoHeaderSize, c.NeededSize(b[:1]))
+	highStart := eliasFanoHeaderSize + packedSize(3, uint(b[4]))
+	ta.Equal(highStart+bitmapHeaderSize, c.NeededSize(b[:eliasFanoHeaderSize]))
+	ta.Equal(len(b), c.NeededSize(b[:highStart+bitmapHeaderSize]))
+
+	// stop early
+	cnt := 0
+	c.Range(b, func(i int, v uint64) bool {
+		cnt++
+		return false
+	})
+	ta.Equal(1, cnt)
+}
@@ -0,0 +1,176 @@
+package qcodec
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ErrAuthFailed indicates an encrypted or signed value fails authentication:
+// it is tampered, or a wrong key or associated data is used.
+var ErrAuthFailed = errors.New("message authentication failed")
+
+// EncryptedCodec encrypts the encoding of an inner Codec with an AEAD cipher,
+// such as AES-GCM.
+// A random nonce is generated for every value.
+//
+// An encoded value is a uvarint length prefix followed by the nonce and the
+// ciphertext.
+// EncodedSize reads only the prefix.
+type EncryptedCodec struct {
+	inner Codec
+	aead  cipher.AEAD
+	ad    []byte
+	rand  io.Reader
+}
+
+// Encrypted creates an *EncryptedCodec that encrypts the encoding of inner
+// with aead, e.g.:
+//     block, _ := aes.NewCipher(key)
+//     aead, _ := cipher.NewGCM(block)
+//     c, _ := Encrypted(U64{}, aead)
+func Encrypted(inner Codec, aead cipher.AEAD) (*EncryptedCodec, error) {
+	if aead == nil {
+		return nil, errors.Errorf("aead is nil")
+	}
+
+	return &EncryptedCodec{
+		inner: inner,
+		aead:  aead,
+		rand:  rand.Reader,
+	}, nil
+}
+
+// WithAD returns a copy of the Codec that binds associated data "ad", such as
+// a record key, to every value.
+// The associated data is authenticated but not stored: a value can only be
+// decoded with the same associated data.
+func (c *EncryptedCodec) WithAD(ad []byte) *EncryptedCodec {
+	cc := *c
+	cc.ad = append([]byte{}, ad...)
+	return &cc
+}
+
+// Encode encrypts the encoding of d by the inner Codec.
+func (c *EncryptedCodec) Encode(d interface{}) []byte {
+	plain := c.inner.Encode(d)
+
+	ns := c.aead.NonceSize()
+	l := ns + len(plain) + c.aead.Overhead()
+
+	rst := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+l)
+	n := binary.PutUvarint(rst, uint64(l))
+	rst = rst[:n+ns]
+
+	nonce := rst[n:]
+	if _, err := io.ReadFull(c.rand, nonce); err != nil {
+		panic(errors.Wrapf(err, "generate nonce"))
+	}
+
+	return c.aead.Seal(rst, nonce, plain, c.ad)
+}
+
+// Decode decrypts and decodes a value.
+// It returns number bytes consumed and the value decoded by the inner Codec.
+// It panics with an error wrapping ErrAuthFailed if authentication fails.
+func (c *EncryptedCodec) Decode(b []byte) (int, interface{}) {
+	n, v, err := c.DecodeChecked(b)
+	if err != nil {
+		panic(err)
+	}
+	return n, v
+}
+
+// DecodeChecked is the same as Decode except it returns an error, which wraps
+// ErrAuthFailed if authentication fails.
+func (c *EncryptedCodec) DecodeChecked(b []byte) (int, interface{}, error) {
+	n, plain, err := c.open(b)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	want, err := neededSizeChecked(c.inner, plain)
+	if err != nil {
+		return 0, nil, err
+	}
+	if want != len(plain) {
+		return 0, nil, errors.Wrapf(ErrCorrupted, "inner size: %d, want: %d", len(plain), want)
+	}
+
+	_, v, err := decodeChecked(c.inner, plain)
+	if err != nil {
+		return 0, nil, err
+	}
+	return n, v, nil
+}
+
+// Size returns the size in byte after encoding v: length prefix, nonce,
+// inner encoding and the authentication tag.
+func (c *EncryptedCodec) Size(d interface{}) int {
+	l := c.aead.NonceSize() + c.inner.Size(d) + c.aead.Overhead()
+
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], uint64(l)) + l
+}
+
+// EncodedSize returns the size of the encoded value by reading the length
+// prefix.
+func (c *EncryptedCodec) EncodedSize(b []byte) int {
+	n, err := lenPrefixedSize(b)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// NeededSize returns the encoded size if the length prefix is complete.
+// It panics if the length prefix is corrupted.
+func (c *EncryptedCodec) NeededSize(b []byte) int {
+	return lenPrefixedNeededSize(b)
+}
+
+// NeededSizeChecked is the same as NeededSize except it returns an error
+// wrapping ErrCorrupted if the length prefix is corrupted.
+func (c *EncryptedCodec) NeededSizeChecked(b []byte) (int, error) {
+	return lenPrefixedNeededSizeChecked(b)
+}
+
+// Compare decrypts two values and compares them with the inner Codec.
+func (c *EncryptedCodec) Compare(a, b []byte) int {
+	_, pa, err := c.open(a)
+	if err != nil {
+		panic(err)
+	}
+	_, pb, err := c.open(b)
+	if err != nil {
+		panic(err)
+	}
+	return CompareEncoded(c.inner, pa, pb)
+}
+
+// open returns the number of bytes consumed and the decrypted inner encoding.
+func (c *EncryptedCodec) open(b []byte) (int, []byte, error) {
+	end, err := lenPrefixedSize(b)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(b) < end {
+		return 0, nil, errors.Wrapf(ErrShortBuffer, "need: %d, got: %d", end, len(b))
+	}
+	_, n := binary.Uvarint(b)
+
+	ns := c.aead.NonceSize()
+	if end-n < ns+c.aead.Overhead() {
+		return 0, nil, errors.Wrapf(ErrAuthFailed, "ciphertext too short: %d", end-n)
+	}
+
+	nonce := b[n : n+ns]
+	plain, err := c.aead.Open(nil, nonce, b[n+ns:end], c.ad)
+	if err != nil {
+		return 0, nil, errors.Wrapf(ErrAuthFailed, "%v", err)
+	}
+	return end, plain, nil
+}
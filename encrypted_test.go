@@ -0,0 +1,169 @@
+package qcodec
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestGCM(t *testing.T, key string) cipher.AEAD {
+	block, err := aes.NewCipher([]byte(key))
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	return aead
+}
+
+func TestEncrypted(t *testing.T) {
+
+	ta := require.New(t)
+
+	aead := newTestGCM(t, "0123456789abcdef")
+
+	xy, err := NewTypeCodec(typeXY{})
+	ta.NoError(err)
+
+	cases := []struct {
+		inner Codec
+		input interface{}
+	}{
+		{String16{}, ""},
+		{String16{}, "secret"},
+		{String16{}, string(bytes.Repeat([]byte("x"), 300))},
+		{U64{}, uint64(0x0102030405060708)},
+		{xy, typeXY{-1, 2}},
+	}
+
+	for i, c := range cases {
+		m, err := Encrypted(c.inner, aead)
+		ta.NoError(err)
+
+		b := m.Encode(c.input)
+		ta.Equal(len(b), m.Size(c.input), "%d-th: case: %+v", i+1, c)
+		ta.Equal(len(b), m.EncodedSize(b))
+		ta.Equal(len(b), m.NeededSize(b))
+		ta.True(len(b) > c.inner.Size(c.input)+aead.NonceSize()+aead.Overhead())
+
+		n, v := m.Decode(append(b, 1))
+		ta.Equal(len(b), n)
+		ta.Equal(c.input, v)
+
+		// nonce is random: same value is encrypted differently
+		ta.NotEqual(b, m.Encode(c.input))
+
+		ta.Equal(0, m.Compare(b, m.Encode(c.input)))
+
+		// not plain text
+		plain := c.inner.Encode(c.input)
+		if len(plain) > 4 {
+			ta.False(bytes.Contains(b, plain))
+		}
+	}
+}
+
+func TestEncryptedAD(t *testing.T) {
+
+	ta := require.New(t)
+
+	aead := newTestGCM(t, "0123456789abcdef")
+
+	m, err := Encrypted(String16{}, aead)
+	ta.NoError(err)
+
+	k1 := m.WithAD([]byte("key1"))
+	k2 := m.WithAD([]byte("key2"))
+
+	b := k1.Encode("hello")
+
+	_, v := k1.Decode(b)
+	ta.Equal("hello", v)
+
+	_, _, err = k2.DecodeChecked(b)
+	ta.Equal(ErrAuthFailed, errors.Cause(err))
+
+	_, _, err = m.DecodeChecked(b)
+	ta.Equal(ErrAuthFailed, errors.Cause(err))
+}
+
+func TestEncryptedError(t *testing.T) {
+
+	ta := require.New(t)
+
+	_, err := Encrypted(U8{}, nil)
+	ta.Error(err)
+
+	m, err := Encrypted(String16{}, newTestGCM(t, "0123456789abcdef"))
+	ta.NoError(err)
+
+	b := m.Encode("hello")
+
+	// every tampered byte after the prefix fails authentication
+	for i := 1; i < len(b); i++ {
+		bad := append([]byte{}, b...)
+		bad[i] ^= 0x01
+
+		_, _, err := m.DecodeChecked(bad)
+		ta.Equal(ErrAuthFailed, errors.Cause(err), "byte: %d", i)
+		testPanic(t, func() { m.Decode(bad) }, "tampered")
+
+		_, err = NewReader(bad).Read(m)
+		ta.Equal(ErrAuthFailed, errors.Cause(err))
+	}
+
+	// wrong key
+	other, err := Encrypted(String16{}, newTestGCM(t, "fedcba9876543210"))
+	ta.NoError(err)
+	_, _, err = other.DecodeChecked(b)
+	ta.Equal(ErrAuthFailed, errors.Cause(err))
+
+	// truncated
+	_, _, err = m.DecodeChecked(b[:len(b)-1])
+	ta.Equal(ErrShortBuffer, errors.Cause(err))
+	ta.Equal(len(b), m.NeededSize(b[:1]))
+	ta.Equal(1, m.NeededSize(nil))
+
+	// corrupted prefix
+	badPrefix := bytes.Repeat([]byte{0x80}, 11)
+	testPanic(t, func() { m.NeededSize(badPrefix) }, "corrupted prefix")
+	_, err = NewReader(badPrefix).Read(m)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+	_, _, err = NewDecoder(m).Feed(badPrefix)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+
+	// too short to contain nonce and tag
+	_, _, err = m.DecodeChecked([]byte{2, 0, 0})
+	ta.Equal(ErrAuthFailed, errors.Cause(err))
+}
+
+func TestEncryptedCompose(t *testing.T) {
+
+	ta := require.New(t)
+
+	m, err := Encrypted(String16{}, newTestGCM(t, "0123456789abcdef0123456789abcdef"))
+	ta.NoError(err)
+
+	strs := []string{"a", "bb", "", "dddd"}
+	a, err := NewArray(m, strs, 3)
+	ta.NoError(err)
+
+	loaded, _, err := UnmarshalArray(m, a.Marshal())
+	ta.NoError(err)
+	for i, s := range strs {
+		ta.Equal(s, loaded.Get(i))
+	}
+
+	d := NewDecoder(m)
+	var got []interface{}
+	for _, s := range strs {
+		for _, x := range m.Encode(s) {
+			vals, _, err := d.Feed([]byte{x})
+			ta.NoError(err)
+			got = append(got, vals...)
+		}
+	}
+	ta.Equal([]interface{}{"a", "bb", "", "dddd"}, got)
+}
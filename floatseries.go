@@ -0,0 +1,200 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+
+	"github.com/pkg/errors"
+)
+
+// floatSeriesHeaderSize is the size of the count and the stream size.
+const floatSeriesHeaderSize = 8
+
+// FloatSeries is a Codec of []float64 with the XOR compression of Facebook
+// Gorilla, for time series of metrics in which a value is often equal or
+// close to the previous one.
+//
+// The first value is stored as is.
+// Every following value is XOR-ed with the previous one, and stored as:
+//
+//     0                            // equal to the previous value
+//     10 <meaningful bits>         // meaningful bits fit in the last window
+//     11 <5-bit leading zeros> <6-bit length> <meaningful bits>
+//                                  // a new window
+//
+// Bits are written most significant first.
+// Values are compared by bits thus NaN with any payload and ±Inf are kept
+// exactly.
+//
+// The encoded layout is, integers are little-endian:
+//
+//     n       uint32 // number of values
+//     size    uint32 // size of the bit stream in byte
+//     stream  bytes  // padded with zeros to a byte
+type FloatSeries struct{}
+
+// Encode converts a []float64 to bytes.
+func (c FloatSeries) Encode(d interface{}) []byte {
+	vals := d.([]float64)
+
+	w := &BitWriter{}
+
+	var prev uint64
+	// no window before the first non-equal value
+	prevLeading, prevTrailing := uint(math.MaxUint8), uint(0)
+
+	for i, f := range vals {
+		v := math.Float64bits(f)
+		if i == 0 {
+			w.WriteBits(v, 64)
+			prev = v
+			continue
+		}
+
+		xor := v ^ prev
+		prev = v
+
+		if xor == 0 {
+			w.WriteBit(false)
+			continue
+		}
+		w.WriteBit(true)
+
+		leading := uint(bits.LeadingZeros64(xor))
+		trailing := uint(bits.TrailingZeros64(xor))
+
+		if leading >= prevLeading && trailing >= prevTrailing {
+			w.WriteBit(false)
+			w.WriteBits(xor>>prevTrailing, 64-prevLeading-prevTrailing)
+			continue
+		}
+
+		if leading > 31 {
+			leading = 31
+		}
+		sig := 64 - leading - trailing
+
+		w.WriteBit(true)
+		w.WriteBits(uint64(leading), 5)
+		// 64 meaningful bits is stored as 0
+		w.WriteBits(uint64(sig), 6)
+		w.WriteBits(xor>>trailing, sig)
+
+		prevLeading, prevTrailing = leading, trailing
+	}
+
+	stream := w.Bytes()
+
+	rst := make([]byte, 0, floatSeriesHeaderSize+len(stream))
+	rst = appendU32(rst, len(vals))
+	rst = appendU32(rst, len(stream))
+	return append(rst, stream...)
+}
+
+// Decode converts bytes to a []float64.
+// It returns number bytes consumed and the slice.
+// It panics if b is malformed.
+func (c FloatSeries) Decode(b []byte) (int, interface{}) {
+	n, v, err := c.DecodeChecked(b)
+	if err != nil {
+		panic(err)
+	}
+	return n, v
+}
+
+// DecodeChecked is the same as Decode except it returns an error wrapping
+// ErrShortBuffer if b or the bit stream in it is truncated.
+func (c FloatSeries) DecodeChecked(b []byte) (int, interface{}, error) {
+
+	size := c.NeededSize(b)
+	if size > len(b) {
+		return 0, nil, errors.Wrapf(ErrShortBuffer, "need: %d, got: %d", size, len(b))
+	}
+
+	cnt := int(binary.LittleEndian.Uint32(b))
+	stream := b[floatSeriesHeaderSize:size]
+
+	// every value takes at least 1 bit and the first takes 64: check cnt
+	// before allocating for it
+	if cnt > 0 && uint64(cnt)+63 > uint64(len(stream))*8 {
+		return 0, nil, errors.Wrapf(ErrShortBuffer, "values: %d, stream bits: %d", cnt, len(stream)*8)
+	}
+
+	r := NewBitReader(stream)
+
+	vals := make([]float64, 0, cnt)
+
+	var prev uint64
+	var leading, trailing uint
+
+	for i := 0; i < cnt; i++ {
+		if i == 0 {
+			v, err := r.ReadBits(64)
+			if err != nil {
+				return 0, nil, err
+			}
+			prev = v
+			vals = append(vals, math.Float64frombits(v))
+			continue
+		}
+
+		changed, err := r.ReadBit()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if changed {
+			newWindow, err := r.ReadBit()
+			if err != nil {
+				return 0, nil, err
+			}
+
+			if newWindow {
+				l, err := r.ReadBits(5)
+				if err != nil {
+					return 0, nil, err
+				}
+				sig, err := r.ReadBits(6)
+				if err != nil {
+					return 0, nil, err
+				}
+				if sig == 0 {
+					sig = 64
+				}
+				if l+sig > 64 {
+					return 0, nil, errors.Wrapf(ErrMalformed, "leading: %d, meaningful bits: %d", l, sig)
+				}
+				leading, trailing = uint(l), 64-uint(l)-uint(sig)
+			}
+
+			x, err := r.ReadBits(64 - leading - trailing)
+			if err != nil {
+				return 0, nil, err
+			}
+			prev ^= x << trailing
+		}
+
+		vals = append(vals, math.Float64frombits(prev))
+	}
+
+	return size, vals, nil
+}
+
+// Size returns the size in byte after encoding v.
+func (c FloatSeries) Size(d interface{}) int {
+	return len(c.Encode(d))
+}
+
+// EncodedSize returns size of the encoded series.
+func (c FloatSeries) EncodedSize(b []byte) int {
+	return floatSeriesHeaderSize + int(binary.LittleEndian.Uint32(b[4:]))
+}
+
+// NeededSize returns the encoded size if the header is complete.
+func (c FloatSeries) NeededSize(b []byte) int {
+	if len(b) < floatSeriesHeaderSize {
+		return floatSeriesHeaderSize
+	}
+	return c.EncodedSize(b)
+}
@@ -0,0 +1,115 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFloatSeries(t *testing.T) {
+
+	ta := require.New(t)
+
+	cases := [][]float64{
+		{},
+		{1.5},
+		{0, 0, 0},
+		{1, 2, 3, 4, 5},
+		{-1.25, 1e300, -1e-300, 5e-324, math.MaxFloat64, math.SmallestNonzeroFloat64},
+		{12, 12, 12.5, 12.5, 13, 24, 0.1, 0.2, 0.30000000000000004},
+		{math.Copysign(0, -1), 0, math.Copysign(0, -1)},
+	}
+
+	c := FloatSeries{}
+
+	for i, input := range cases {
+		b := c.Encode(input)
+		ta.Equal(len(b), c.Size(input), "%d-th: case: %v", i+1, input)
+		ta.Equal(len(b), c.EncodedSize(b))
+		ta.Equal(len(b), c.NeededSize(b))
+
+		n, v := c.Decode(append(b, 1, 2))
+		ta.Equal(len(b), n)
+		ta.Equal(floatBits(input), floatBits(v.([]float64)), "%d-th: case: %v", i+1, input)
+	}
+}
+
+func TestFloatSeriesSpecial(t *testing.T) {
+
+	ta := require.New(t)
+
+	payloadNaN := math.Float64frombits(0x7ff8000000000abc)
+
+	input := []float64{
+		math.NaN(), math.Inf(1), math.Inf(-1), payloadNaN, 1, math.NaN(),
+		-math.NaN(), payloadNaN, payloadNaN, math.Inf(-1),
+	}
+
+	c := FloatSeries{}
+	_, v := c.Decode(c.Encode(input))
+	ta.Equal(floatBits(input), floatBits(v.([]float64)))
+}
+
+func TestFloatSeriesCompact(t *testing.T) {
+
+	ta := require.New(t)
+
+	c := FloatSeries{}
+
+	// constant: 1 bit each
+	same := make([]float64, 800)
+	for i := range same {
+		same[i] = 42.5
+	}
+	ta.Equal(floatSeriesHeaderSize+8+100, len(c.Encode(same)))
+
+	// slowly changing gauge
+	gauge := make([]float64, 1000)
+	for i := range gauge {
+		gauge[i] = float64(100 + i%4)
+	}
+	ta.True(len(c.Encode(gauge)) < 8*len(gauge)/3, "size: %d", len(c.Encode(gauge)))
+}
+
+func TestFloatSeriesError(t *testing.T) {
+
+	ta := require.New(t)
+
+	c := FloatSeries{}
+
+	b := c.Encode([]float64{1, 2, 3.3, 4})
+
+	_, _, err := c.DecodeChecked(b[:len(b)-1])
+	ta.Equal(ErrShortBuffer, errors.Cause(err))
+
+	ta.Equal(floatSeriesHeaderSize, c.NeededSize(b[:3]))
+	ta.Equal(len(b), c.NeededSize(b[:floatSeriesHeaderSize]))
+
+	// more values than the stream holds
+	bad := append([]byte{}, b...)
+	bad[0] = 100
+	_, _, err = c.DecodeChecked(bad)
+	ta.Equal(ErrShortBuffer, errors.Cause(err))
+	testPanic(t, func() { c.Decode(bad) }, "truncated stream")
+
+	// a huge count is rejected before allocating
+	binary.LittleEndian.PutUint32(bad, math.MaxUint32)
+	_, _, err = c.DecodeChecked(bad)
+	ta.Equal(ErrShortBuffer, errors.Cause(err))
+
+	_, _, err = c.DecodeChecked([]byte{1, 0, 0, 0, 7, 0, 0, 0, 1, 2, 3, 4, 5, 6, 7})
+	ta.Equal(ErrShortBuffer, errors.Cause(err))
+
+	testPanic(t, func() { c.Encode([]float32{1}) }, "not []float64")
+}
+
+func floatBits(fs []float64) []uint64 {
+	rst := make([]uint64, len(fs))
+	for i, f := range fs {
+		rst[i] = math.Float64bits(f)
+	}
+	return rst
+}
@@ -0,0 +1,169 @@
+package qcodec
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// forBlockSize is the number of values in a FrameOfRef block.
+const forBlockSize = 128
+
+// forBlockHeaderSize is the size of min and bit width of a block.
+const forBlockHeaderSize = 9
+
+// FrameOfRef is a Codec of integer slice that is efficient when values in a
+// small range.
+// It accepts any slice GetSliceEltCodec accepts, such as []uint32 or []int64,
+// and decodes to the same type.
+//
+// Values are split into blocks of 128.
+// A block stores the minimum value and the differences from it with the bit
+// width of the largest difference.
+// An element is accessible with Get without decoding its block.
+//
+// Signed integers have the sign bit flipped thus they are in the same order
+// as unsigned.
+//
+// The encoded layout is, integers are little-endian:
+//
+//     kind     uint8       // reflect.Kind of element
+//     n        uint32      // number of elements
+//     offsets  uint32 * (nblocks+1)
+//                          // offset of every block, relative to the end of
+//                          // offsets, and the end of the last block
+//     blocks:
+//       min    uint64
+//       width  uint8
+//       packed ceil(width*count/8) bytes
+type FrameOfRef struct{}
+
+// Encode converts a slice of integers to bytes.
+// It panics if d is not a slice of integer of fixed size.
+func (c FrameOfRef) Encode(d interface{}) []byte {
+	vals, kind, err := toOrderedUint64s(d)
+	if err != nil {
+		panic(err)
+	}
+
+	return appendIntBlocks(nil, kind, vals, forBlockSize, appendFORBlock)
+}
+
+// Decode converts bytes to a slice of integers.
+// It returns number bytes consumed and the slice.
+func (c FrameOfRef) Decode(b []byte) (int, interface{}) {
+	h := readIntBlocks(b, forBlockSize)
+
+	vals := make([]uint64, 0, h.n)
+	for blk := 0; blk < h.nblocks; blk++ {
+		vals = appendFORValues(vals, h, b, blk)
+	}
+
+	return h.encodedSize(b), fromOrderedUint64s(h.kind, vals)
+}
+
+// Size returns the size in byte after encoding v.
+func (c FrameOfRef) Size(d interface{}) int {
+	vals, _, err := toOrderedUint64s(d)
+	if err != nil {
+		panic(err)
+	}
+
+	nblocks := (len(vals) + forBlockSize - 1) / forBlockSize
+	size := intBlocksHeaderSize + 4*(nblocks+1)
+
+	for i := 0; i < len(vals); i += forBlockSize {
+		end := i + forBlockSize
+		if end > len(vals) {
+			end = len(vals)
+		}
+		_, width := forRange(vals[i:end])
+		size += forBlockHeaderSize + packedSize(end-i, width)
+	}
+	return size
+}
+
+// EncodedSize returns size of the encoded slice.
+func (c FrameOfRef) EncodedSize(b []byte) int {
+	return readIntBlocks(b, forBlockSize).encodedSize(b)
+}
+
+// NeededSize returns the encoded size if the header and offsets are complete.
+func (c FrameOfRef) NeededSize(b []byte) int {
+	return intBlocksNeededSize(b, forBlockSize)
+}
+
+// Len returns the number of elements in the encoded slice.
+func (c FrameOfRef) Len(b []byte) int {
+	return readIntBlocks(b, forBlockSize).n
+}
+
+// Get returns the i-th element of the encoded slice, without decoding others.
+// It panics if i is out of range.
+func (c FrameOfRef) Get(b []byte, i int) interface{} {
+	h := readIntBlocks(b, forBlockSize)
+	if i < 0 || i >= h.n {
+		panic(errors.Errorf("index out of range: %d, len: %d", i, h.n))
+	}
+
+	blk := h.block(b, i/forBlockSize)
+	min := binary.LittleEndian.Uint64(blk)
+	width := uint(blk[8])
+	u := min + unpackBits(blk[forBlockHeaderSize:], i%forBlockSize, width)
+
+	return fromOrdered(h.kind, u)
+}
+
+// DecodeBlock decodes the blk-th block of 128 elements, or less for the last
+// block, and returns a slice of them.
+// It panics if blk is out of range.
+func (c FrameOfRef) DecodeBlock(b []byte, blk int) interface{} {
+	h := readIntBlocks(b, forBlockSize)
+	if blk < 0 || blk >= h.nblocks {
+		panic(errors.Errorf("block out of range: %d, blocks: %d", blk, h.nblocks))
+	}
+	return fromOrderedUint64s(h.kind, appendFORValues(nil, h, b, blk))
+}
+
+// appendFORValues appends the values of the blk-th block to vals.
+func appendFORValues(vals []uint64, h intBlocks, b []byte, blk int) []uint64 {
+	cnt := h.count(blk)
+
+	p := h.block(b, blk)
+	min := binary.LittleEndian.Uint64(p)
+	width := uint(p[8])
+	p = p[forBlockHeaderSize:]
+
+	for i := 0; i < cnt; i++ {
+		vals = append(vals, min+unpackBits(p, i, width))
+	}
+	return vals
+}
+
+// forRange returns the min value and the bit width of the largest difference
+// from min.
+func forRange(vals []uint64) (uint64, uint) {
+	min, max := vals[0], vals[0]
+	for _, v := range vals {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	return min, bitWidth(max - min)
+}
+
+func appendFORBlock(b []byte, vals []uint64) []byte {
+	min, width := forRange(vals)
+
+	deltas := make([]uint64, len(vals))
+	for i, v := range vals {
+		deltas[i] = v - min
+	}
+
+	b = appendU64(b, min)
+	b = append(b, byte(width))
+	return packBits(b, deltas, width)
+}
@@ -0,0 +1,110 @@
+package qcodec
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameOfRef(t *testing.T) {
+
+	ta := require.New(t)
+
+	seq := func(n int, start, step uint32) []uint32 {
+		rst := make([]uint32, n)
+		for i := range rst {
+			rst[i] = start + uint32(i)*step
+		}
+		return rst
+	}
+
+	cases := []interface{}{
+		[]uint32{},
+		[]uint32{5},
+		[]uint32{7, 7, 7},
+		seq(300, 1000000, 3),
+		[]uint64{0, math.MaxUint64, 1},
+		[]int64{-5, 3, math.MinInt64, math.MaxInt64},
+		[]int8{-1, 1, -128},
+		[]int32{-100, -99, -98},
+		[]uint16{1, 2, 65535},
+		[]uint8{255, 0},
+	}
+
+	c := FrameOfRef{}
+
+	for i, input := range cases {
+		b := c.Encode(input)
+		ta.Equal(len(b), c.Size(input), "%d-th: case: %v", i+1, input)
+		ta.Equal(len(b), c.EncodedSize(b))
+		ta.Equal(len(b), c.NeededSize(b))
+
+		n, v := c.Decode(append(b, 1, 2))
+		ta.Equal(len(b), n)
+		ta.Equal(input, v)
+	}
+}
+
+func TestFrameOfRefGet(t *testing.T) {
+
+	ta := require.New(t)
+
+	c := FrameOfRef{}
+
+	vals := make([]int32, 300)
+	for i := range vals {
+		vals[i] = int32(i*7%50) - 20
+	}
+
+	b := c.Encode(vals)
+	ta.Equal(300, c.Len(b))
+
+	for i, v := range vals {
+		ta.Equal(v, c.Get(b, i))
+	}
+
+	ta.Equal(vals[:128], c.DecodeBlock(b, 0))
+	ta.Equal(vals[128:256], c.DecodeBlock(b, 1))
+	ta.Equal(vals[256:], c.DecodeBlock(b, 2))
+
+	testPanic(t, func() { c.Get(b, -1) }, "negative index")
+	testPanic(t, func() { c.Get(b, 300) }, "index out of range")
+	testPanic(t, func() { c.DecodeBlock(b, 3) }, "block out of range")
+
+	// values in [-20, 30) use 6 bits each, less than a byte
+	ta.True(len(b) < len(vals))
+}
+
+func TestFrameOfRefSize(t *testing.T) {
+
+	ta := require.New(t)
+
+	c := FrameOfRef{}
+
+	vals := make([]uint64, 256)
+	for i := range vals {
+		vals[i] = 1 << 40
+	}
+	vals[255] += 15
+
+	// block 0: width 0; block 1: width 4
+	b := c.Encode(vals)
+	ta.Equal(intBlocksHeaderSize+4*3+forBlockHeaderSize*2+64, len(b))
+
+	ta.Equal(intBlocksHeaderSize, c.NeededSize(nil))
+	ta.Equal(intBlocksHeaderSize+4*3, c.NeededSize(b[:intBlocksHeaderSize]))
+	ta.Equal(len(b), c.NeededSize(b[:intBlocksHeaderSize+4*3]))
+
+	d := NewDecoder(c)
+	var got []interface{}
+	for _, x := range b {
+		vs, _, err := d.Feed([]byte{x})
+		ta.NoError(err)
+		got = append(got, vs...)
+	}
+	ta.Equal([]interface{}{vals}, got)
+
+	testPanic(t, func() { c.Encode([]int{1}) }, "int is not fixed size")
+	testPanic(t, func() { c.Encode(1) }, "not slice")
+}
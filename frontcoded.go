@@ -0,0 +1,328 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// defaultFrontCodedInterval is the number of strings between two restarts.
+const defaultFrontCodedInterval = 16
+
+// frontCodedHeaderSize is the size of type, interval and count.
+const frontCodedHeaderSize = 9
+
+// element types of FrontCoded.
+const (
+	frontCodedString byte = iota
+	frontCodedBytes
+)
+
+// FrontCoded is a Codec of sorted []string or [][]byte that stores every
+// string as the length of the prefix shared with the previous one and the
+// remaining suffix, thus sorted keys with long common prefixes are compact.
+// It decodes to the same type as encoded.
+//
+// Every "Interval"-th string is a restart that is stored in full, and its
+// offset is kept in a restart index.
+// Get decodes at most Interval strings, and Search does a binary search on
+// restarts and then a scan of one block.
+//
+// The encoded layout is, integers are little-endian:
+//
+//     type      uint8  // 0: string, 1: []byte
+//     interval  uint32
+//     n         uint32 // number of strings
+//     restarts  uint32 * ceil(n/interval) // offsets in data
+//     size      uint32 // size of data
+//     data:  n times
+//       shared  uvarint // 0 for restarts
+//       length  uvarint // length of suffix
+//       suffix  bytes
+//
+// The zero value is a codec with interval 16.
+type FrontCoded struct {
+	// Interval is the number of strings between two restarts.
+	// 0 means 16.
+	Interval int
+}
+
+// Encode converts a sorted []string or [][]byte to bytes.
+// It panics with an error wrapping ErrNotSorted if d is not sorted.
+func (c FrontCoded) Encode(d interface{}) []byte {
+	typ, strs := toFrontCodedStrings(d)
+	interval := c.interval()
+
+	var restarts, data []byte
+	var buf [binary.MaxVarintLen64]byte
+
+	for i, s := range strs {
+		shared := 0
+		if i > 0 {
+			prev := strs[i-1]
+			if s < prev {
+				panic(errors.Wrapf(ErrNotSorted, "at: %d, %q < %q", i, s, prev))
+			}
+			if i%interval != 0 {
+				shared = commonPrefixLen(prev, s)
+			}
+		}
+
+		if i%interval == 0 {
+			restarts = appendU32(restarts, len(data))
+		}
+
+		n := binary.PutUvarint(buf[:], uint64(shared))
+		data = append(data, buf[:n]...)
+		n = binary.PutUvarint(buf[:], uint64(len(s)-shared))
+		data = append(data, buf[:n]...)
+		data = append(data, s[shared:]...)
+	}
+
+	rst := make([]byte, 0, frontCodedHeaderSize+len(restarts)+4+len(data))
+	rst = append(rst, typ)
+	rst = appendU32(rst, interval)
+	rst = appendU32(rst, len(strs))
+	rst = append(rst, restarts...)
+	rst = appendU32(rst, len(data))
+	return append(rst, data...)
+}
+
+// Decode converts bytes to a []string or [][]byte.
+// It returns number bytes consumed and the slice.
+func (c FrontCoded) Decode(b []byte) (int, interface{}) {
+	fc := readFrontCoded(b)
+
+	strs := make([]string, 0, fc.n)
+	fc.scan(0, func(i int, s string) bool {
+		strs = append(strs, s)
+		return true
+	})
+
+	if fc.typ == frontCodedBytes {
+		rst := make([][]byte, len(strs))
+		for i, s := range strs {
+			rst[i] = []byte(s)
+		}
+		return fc.size, rst
+	}
+	return fc.size, strs
+}
+
+// Size returns the size in byte after encoding v.
+func (c FrontCoded) Size(d interface{}) int {
+	return len(c.Encode(d))
+}
+
+// EncodedSize returns size of the encoded list.
+func (c FrontCoded) EncodedSize(b []byte) int {
+	return readFrontCoded(b).size
+}
+
+// NeededSize returns the encoded size if the header and restarts are
+// complete.
+func (c FrontCoded) NeededSize(b []byte) int {
+	if len(b) < frontCodedHeaderSize {
+		return frontCodedHeaderSize
+	}
+	_, _, dataStart := frontCodedLayout(b)
+	if len(b) < dataStart {
+		return dataStart
+	}
+	return c.EncodedSize(b)
+}
+
+// Len returns the number of strings.
+func (c FrontCoded) Len(b []byte) int {
+	return readFrontCoded(b).n
+}
+
+// Get returns the i-th string, a string or []byte as encoded.
+// It decodes strings from the restart before i.
+// It panics if i is out of range.
+func (c FrontCoded) Get(b []byte, i int) interface{} {
+	fc := readFrontCoded(b)
+	if i < 0 || i >= fc.n {
+		panic(errors.Errorf("index out of range: %d, len: %d", i, fc.n))
+	}
+
+	var rst string
+	fc.scan(i-i%fc.interval, func(j int, s string) bool {
+		rst = s
+		return j < i
+	})
+
+	if fc.typ == frontCodedBytes {
+		return []byte(rst)
+	}
+	return rst
+}
+
+// Search returns the index of the first string not less than key, a string
+// or []byte, and whether it equals key.
+func (c FrontCoded) Search(b []byte, key interface{}) (int, bool) {
+	fc := readFrontCoded(b)
+
+	var k string
+	switch x := key.(type) {
+	case string:
+		k = x
+	case []byte:
+		k = string(x)
+	default:
+		panic(errors.Wrapf(ErrUnknownEltType, "key type: %T", key))
+	}
+
+	nrestarts := (fc.n + fc.interval - 1) / fc.interval
+
+	// the first restart not less than key, the result is it or in the block
+	// before it.
+	r := sort.Search(nrestarts, func(r int) bool {
+		return fc.restart(r) >= k
+	})
+	if r == 0 {
+		return 0, fc.n > 0 && fc.restart(0) == k
+	}
+
+	idx, found := fc.n, false
+	fc.scan((r-1)*fc.interval, func(j int, s string) bool {
+		if s >= k {
+			idx, found = j, s == k
+			return false
+		}
+		return true
+	})
+	return idx, found
+}
+
+func (c FrontCoded) interval() int {
+	if c.Interval < 0 {
+		panic(errors.Wrapf(ErrInvalidInterval, "interval: %d", c.Interval))
+	}
+	if c.Interval == 0 {
+		return defaultFrontCodedInterval
+	}
+	return c.Interval
+}
+
+func toFrontCodedStrings(d interface{}) (byte, []string) {
+	switch s := d.(type) {
+	case []string:
+		return frontCodedString, s
+	case [][]byte:
+		strs := make([]string, len(s))
+		for i, x := range s {
+			strs[i] = string(x)
+		}
+		return frontCodedBytes, strs
+	}
+	panic(errors.Wrapf(ErrUnknownEltType, "type: %T", d))
+}
+
+func commonPrefixLen(a, b string) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// frontCodedLayout returns interval, number of strings and the start of
+// data.
+func frontCodedLayout(b []byte) (int, int, int) {
+	interval := int(binary.LittleEndian.Uint32(b[1:]))
+	if interval <= 0 {
+		panic(errors.Wrapf(ErrMalformed, "interval: %d", interval))
+	}
+	n := int(binary.LittleEndian.Uint32(b[5:]))
+	nrestarts := (n + interval - 1) / interval
+	return interval, n, frontCodedHeaderSize + 4*nrestarts + 4
+}
+
+// frontCoded is a view of an encoded FrontCoded list.
+type frontCoded struct {
+	typ      byte
+	interval int
+	n        int
+	restarts []byte
+	data     []byte
+	size     int
+}
+
+func readFrontCoded(b []byte) *frontCoded {
+	interval, n, dataStart := frontCodedLayout(b)
+	size := dataStart + int(binary.LittleEndian.Uint32(b[dataStart-4:]))
+
+	return &frontCoded{
+		typ:      b[0],
+		interval: interval,
+		n:        n,
+		restarts: b[frontCodedHeaderSize : dataStart-4],
+		data:     b[dataStart:size],
+		size:     size,
+	}
+}
+
+// restart returns the r-th restart string.
+func (fc *frontCoded) restart(r int) string {
+	off := int(binary.LittleEndian.Uint32(fc.restarts[r*4:]))
+	_, s, err := fc.entry(off, "")
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// entry decodes the entry at off with the previous string prev.
+// It returns the offset of the next entry and the string.
+func (fc *frontCoded) entry(off int, prev string) (int, string, error) {
+	shared, n := binary.Uvarint(fc.data[off:])
+	if n <= 0 {
+		return 0, "", errors.Wrapf(ErrMalformed, "invalid shared length at: %d", off)
+	}
+	off += n
+
+	l, n := binary.Uvarint(fc.data[off:])
+	if n <= 0 {
+		return 0, "", errors.Wrapf(ErrMalformed, "invalid suffix length at: %d", off)
+	}
+	off += n
+
+	if shared > uint64(len(prev)) || l > uint64(len(fc.data)-off) {
+		return 0, "", errors.Wrapf(ErrMalformed, "shared: %d, suffix length: %d at: %d", shared, l, off)
+	}
+
+	var sb strings.Builder
+	sb.Grow(int(shared + l))
+	sb.WriteString(prev[:shared])
+	sb.Write(fc.data[off : off+int(l)])
+	return off + int(l), sb.String(), nil
+}
+
+// scan calls f with strings from the i-th, which must be a restart, until f
+// returns false.
+func (fc *frontCoded) scan(i int, f func(i int, s string) bool) {
+	if i >= fc.n {
+		return
+	}
+
+	off := int(binary.LittleEndian.Uint32(fc.restarts[i/fc.interval*4:]))
+	prev := ""
+	for ; i < fc.n; i++ {
+		next, s, err := fc.entry(off, prev)
+		if err != nil {
+			panic(err)
+		}
+		if !f(i, s) {
+			return
+		}
+		off, prev = next, s
+	}
+}
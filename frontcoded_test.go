@@ -0,0 +1,119 @@
+package qcodec
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrontCoded(t *testing.T) {
+
+	ta := require.New(t)
+
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("/users/profile/%06d", i*7)
+	}
+
+	cases := []interface{}{
+		[]string{},
+		[]string{""},
+		[]string{"", "", "a"},
+		[]string{"a", "ab", "abc", "abd", "b", "ba"},
+		keys,
+		[][]byte{},
+		[][]byte{[]byte("x"), []byte("xy"), []byte("xyz")},
+	}
+
+	codecs := []FrontCoded{{}, {Interval: 1}, {Interval: 3}}
+
+	for _, c := range codecs {
+		for i, input := range cases {
+			b := c.Encode(input)
+			ta.Equal(len(b), c.Size(input), "%d-th: codec: %+v", i+1, c)
+			ta.Equal(len(b), c.EncodedSize(b))
+			ta.Equal(len(b), c.NeededSize(b))
+
+			n, v := c.Decode(append(b, 1))
+			ta.Equal(len(b), n)
+			ta.Equal(input, v)
+
+			_, strs := toFrontCodedStrings(input)
+			ta.Equal(len(strs), c.Len(b))
+			for j := range strs {
+				switch x := input.(type) {
+				case []string:
+					ta.Equal(x[j], c.Get(b, j))
+				case [][]byte:
+					ta.Equal(x[j], c.Get(b, j))
+				}
+			}
+			testPanic(t, func() { c.Get(b, len(strs)) }, "out of range")
+		}
+	}
+
+	// shared prefixes are stored once
+	b := FrontCoded{}.Encode(keys)
+	ta.True(len(b) < String16{}.Size(keys[0])*len(keys)/2, "size: %d", len(b))
+}
+
+func TestFrontCodedSearch(t *testing.T) {
+
+	ta := require.New(t)
+
+	keys := []string{"", "a", "a", "ab", "abc", "abd", "b", "ba", "bb", "c", "cat", "d"}
+
+	probes := []string{"", "a", "aa", "ab", "abb", "abc", "abz", "b", "bab", "bb", "bc", "c", "ca", "cat", "cb", "d", "e", "\xff"}
+
+	for _, c := range []FrontCoded{{}, {Interval: 1}, {Interval: 2}, {Interval: 5}} {
+		b := c.Encode(keys)
+
+		for _, p := range probes {
+			want := sort.SearchStrings(keys, p)
+			idx, found := c.Search(b, p)
+			ta.Equal(want, idx, "probe: %q, codec: %+v", p, c)
+			ta.Equal(want < len(keys) && keys[want] == p, found)
+
+			idx2, found2 := c.Search(b, []byte(p))
+			ta.Equal(idx, idx2)
+			ta.Equal(found, found2)
+		}
+	}
+
+	b := FrontCoded{}.Encode([]string{})
+	idx, found := FrontCoded{}.Search(b, "a")
+	ta.Equal(0, idx)
+	ta.False(found)
+
+	testPanic(t, func() { FrontCoded{}.Search(b, 1) }, "invalid key type")
+}
+
+func TestFrontCodedError(t *testing.T) {
+
+	ta := require.New(t)
+
+	c := FrontCoded{}
+
+	func() {
+		defer func() {
+			ta.Equal(ErrNotSorted, errors.Cause(recover().(error)))
+		}()
+		c.Encode([]string{"b", "a"})
+	}()
+
+	testPanic(t, func() { c.Encode([]int{1}) }, "unknown type")
+	testPanic(t, func() { FrontCoded{Interval: -1}.Encode([]string{}) }, "negative interval")
+
+	b := c.Encode([]string{"a", "b"})
+	ta.Equal(frontCodedHeaderSize, c.NeededSize(b[:2]))
+	ta.Equal(frontCodedHeaderSize+4+4, c.NeededSize(b[:frontCodedHeaderSize]))
+	ta.Equal(len(b), c.NeededSize(b[:frontCodedHeaderSize+8]))
+
+	// suffix length beyond data
+	bad := append([]byte{}, b...)
+	bad[frontCodedHeaderSize+8+1] = 100
+	testPanic(t, func() { c.Decode(bad) }, "malformed")
+}
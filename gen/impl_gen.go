@@ -41,6 +41,19 @@ func (c {{.Name}}) Size(d interface{}) int {
 func (c {{.Name}}) EncodedSize(b []byte) int {
 	return {{.ValLen}}
 }
+
+// Compare decodes and compares two encoded {{.ValType}}.
+func (c {{.Name}}) Compare(a, b []byte) int {
+	x := {{.ValType}}(binary.LittleEndian.{{.Codec}}(a))
+	y := {{.ValType}}(binary.LittleEndian.{{.Codec}}(b))
+	if x < y {
+		return -1
+	}
+	if x > y {
+		return 1
+	}
+	return 0
+}
 `
 
 var testHead = `package qcodec
@@ -99,6 +112,16 @@ func Test{{.Name}}(t *testing.T) {
 			t.Fatalf("%d-th: decoded size: input: %v; want: %v; actual: %v",
 				i+1, c.input, c.wantsize, n)
 		}
+
+		if m.Compare(rst, rst) != 0 {
+			t.Fatalf("%d-th: compare: input: %v; want: 0", i+1, c.input)
+		}
+	}
+
+	v0b := m.Encode({{.ValType}}(0))
+	v1b := m.Encode({{.ValType}}(1))
+	if m.Compare(v0b, v1b) != -1 || m.Compare(v1b, v0b) != 1 {
+		t.Fatalf("compare: 0 should be less than 1")
 	}
 }
 `
@@ -36,6 +36,19 @@ func (c U16) EncodedSize(b []byte) int {
 	return 2
 }
 
+// Compare decodes and compares two encoded uint16.
+func (c U16) Compare(a, b []byte) int {
+	x := binary.LittleEndian.Uint16(a)
+	y := binary.LittleEndian.Uint16(b)
+	if x < y {
+		return -1
+	}
+	if x > y {
+		return 1
+	}
+	return 0
+}
+
 // U32 converts uint32 to slice of 4 bytes and back.
 type U32 struct{}
 
@@ -68,6 +81,19 @@ func (c U32) EncodedSize(b []byte) int {
 	return 4
 }
 
+// Compare decodes and compares two encoded uint32.
+func (c U32) Compare(a, b []byte) int {
+	x := binary.LittleEndian.Uint32(a)
+	y := binary.LittleEndian.Uint32(b)
+	if x < y {
+		return -1
+	}
+	if x > y {
+		return 1
+	}
+	return 0
+}
+
 // U64 converts uint64 to slice of 8 bytes and back.
 type U64 struct{}
 
@@ -100,6 +126,19 @@ func (c U64) EncodedSize(b []byte) int {
 	return 8
 }
 
+// Compare decodes and compares two encoded uint64.
+func (c U64) Compare(a, b []byte) int {
+	x := binary.LittleEndian.Uint64(a)
+	y := binary.LittleEndian.Uint64(b)
+	if x < y {
+		return -1
+	}
+	if x > y {
+		return 1
+	}
+	return 0
+}
+
 // I16 converts int16 to slice of 2 bytes and back.
 type I16 struct{}
 
@@ -132,6 +171,19 @@ func (c I16) EncodedSize(b []byte) int {
 	return 2
 }
 
+// Compare decodes and compares two encoded int16.
+func (c I16) Compare(a, b []byte) int {
+	x := int16(binary.LittleEndian.Uint16(a))
+	y := int16(binary.LittleEndian.Uint16(b))
+	if x < y {
+		return -1
+	}
+	if x > y {
+		return 1
+	}
+	return 0
+}
+
 // I32 converts int32 to slice of 4 bytes and back.
 type I32 struct{}
 
@@ -164,6 +216,19 @@ func (c I32) EncodedSize(b []byte) int {
 	return 4
 }
 
+// Compare decodes and compares two encoded int32.
+func (c I32) Compare(a, b []byte) int {
+	x := int32(binary.LittleEndian.Uint32(a))
+	y := int32(binary.LittleEndian.Uint32(b))
+	if x < y {
+		return -1
+	}
+	if x > y {
+		return 1
+	}
+	return 0
+}
+
 // I64 converts int64 to slice of 8 bytes and back.
 type I64 struct{}
 
@@ -195,3 +260,16 @@ func (c I64) Size(d interface{}) int {
 func (c I64) EncodedSize(b []byte) int {
 	return 8
 }
+
+// Compare decodes and compares two encoded int64.
+func (c I64) Compare(a, b []byte) int {
+	x := int64(binary.LittleEndian.Uint64(a))
+	y := int64(binary.LittleEndian.Uint64(b))
+	if x < y {
+		return -1
+	}
+	if x > y {
+		return 1
+	}
+	return 0
+}
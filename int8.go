@@ -23,6 +23,12 @@ func (c I8) Size(d interface{}) int {
 func (c I8) EncodedSize(b []byte) int {
 	return 1
 }
+
+// Compare decodes and compares two encoded int8.
+func (c I8) Compare(a, b []byte) int {
+	return cmpInt64(int64(int8(a[0])), int64(int8(b[0])))
+}
+
 // U8 converts int8 to slice of 1 byte and back.
 type U8 struct{}
 
@@ -46,3 +52,8 @@ func (c U8) Size(d interface{}) int {
 func (c U8) EncodedSize(b []byte) int {
 	return 1
 }
+
+// Compare compares two encoded uint8.
+func (c U8) Compare(a, b []byte) int {
+	return cmpUint64(uint64(a[0]), uint64(b[0]))
+}
@@ -54,6 +54,16 @@ func TestU16(t *testing.T) {
 			t.Fatalf("%d-th: decoded size: input: %v; want: %v; actual: %v",
 				i+1, c.input, c.wantsize, n)
 		}
+
+		if m.Compare(rst, rst) != 0 {
+			t.Fatalf("%d-th: compare: input: %v; want: 0", i+1, c.input)
+		}
+	}
+
+	v0b := m.Encode(uint16(0))
+	v1b := m.Encode(uint16(1))
+	if m.Compare(v0b, v1b) != -1 || m.Compare(v1b, v0b) != 1 {
+		t.Fatalf("compare: 0 should be less than 1")
 	}
 }
 
@@ -105,6 +115,16 @@ func TestU32(t *testing.T) {
 			t.Fatalf("%d-th: decoded size: input: %v; want: %v; actual: %v",
 				i+1, c.input, c.wantsize, n)
 		}
+
+		if m.Compare(rst, rst) != 0 {
+			t.Fatalf("%d-th: compare: input: %v; want: 0", i+1, c.input)
+		}
+	}
+
+	v0b := m.Encode(uint32(0))
+	v1b := m.Encode(uint32(1))
+	if m.Compare(v0b, v1b) != -1 || m.Compare(v1b, v0b) != 1 {
+		t.Fatalf("compare: 0 should be less than 1")
 	}
 }
 
@@ -156,6 +176,16 @@ func TestU64(t *testing.T) {
 			t.Fatalf("%d-th: decoded size: input: %v; want: %v; actual: %v",
 				i+1, c.input, c.wantsize, n)
 		}
+
+		if m.Compare(rst, rst) != 0 {
+			t.Fatalf("%d-th: compare: input: %v; want: 0", i+1, c.input)
+		}
+	}
+
+	v0b := m.Encode(uint64(0))
+	v1b := m.Encode(uint64(1))
+	if m.Compare(v0b, v1b) != -1 || m.Compare(v1b, v0b) != 1 {
+		t.Fatalf("compare: 0 should be less than 1")
 	}
 }
 
@@ -207,6 +237,16 @@ func TestI16(t *testing.T) {
 			t.Fatalf("%d-th: decoded size: input: %v; want: %v; actual: %v",
 				i+1, c.input, c.wantsize, n)
 		}
+
+		if m.Compare(rst, rst) != 0 {
+			t.Fatalf("%d-th: compare: input: %v; want: 0", i+1, c.input)
+		}
+	}
+
+	v0b := m.Encode(int16(0))
+	v1b := m.Encode(int16(1))
+	if m.Compare(v0b, v1b) != -1 || m.Compare(v1b, v0b) != 1 {
+		t.Fatalf("compare: 0 should be less than 1")
 	}
 }
 
@@ -258,6 +298,16 @@ func TestI32(t *testing.T) {
 			t.Fatalf("%d-th: decoded size: input: %v; want: %v; actual: %v",
 				i+1, c.input, c.wantsize, n)
 		}
+
+		if m.Compare(rst, rst) != 0 {
+			t.Fatalf("%d-th: compare: input: %v; want: 0", i+1, c.input)
+		}
+	}
+
+	v0b := m.Encode(int32(0))
+	v1b := m.Encode(int32(1))
+	if m.Compare(v0b, v1b) != -1 || m.Compare(v1b, v0b) != 1 {
+		t.Fatalf("compare: 0 should be less than 1")
 	}
 }
 
@@ -309,5 +359,15 @@ func TestI64(t *testing.T) {
 			t.Fatalf("%d-th: decoded size: input: %v; want: %v; actual: %v",
 				i+1, c.input, c.wantsize, n)
 		}
+
+		if m.Compare(rst, rst) != 0 {
+			t.Fatalf("%d-th: compare: input: %v; want: 0", i+1, c.input)
+		}
+	}
+
+	v0b := m.Encode(int64(0))
+	v1b := m.Encode(int64(1))
+	if m.Compare(v0b, v1b) != -1 || m.Compare(v1b, v0b) != 1 {
+		t.Fatalf("compare: 0 should be less than 1")
 	}
 }
@@ -0,0 +1,302 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// signBit flips the sign of an int64 stored in uint64 so that the order of
+// signed integers is kept as unsigned integers.
+const signBit = uint64(1) << 63
+
+// toOrderedUint64s converts a slice of integers to uint64 preserving the
+// order: unsigned integers are kept as is, signed integers have the sign bit
+// flipped.
+// The slice must be one that GetSliceEltCodec accepts.
+// It returns the converted values and the element kind.
+func toOrderedUint64s(s interface{}) ([]uint64, reflect.Kind, error) {
+
+	if _, err := GetSliceEltCodec(s); err != nil {
+		return nil, reflect.Invalid, errors.Wrapf(err, "type: %T", s)
+	}
+
+	switch v := s.(type) {
+	case []uint64:
+		return append([]uint64{}, v...), reflect.Uint64, nil
+	case []uint32:
+		rst := make([]uint64, len(v))
+		for i, x := range v {
+			rst[i] = uint64(x)
+		}
+		return rst, reflect.Uint32, nil
+	}
+
+	sl := reflect.ValueOf(s)
+	k := sl.Type().Elem().Kind()
+
+	rst := make([]uint64, sl.Len())
+	for i := range rst {
+		rst[i] = toOrdered(k, sl.Index(i))
+	}
+	return rst, k, nil
+}
+
+// fromOrderedUint64s converts values built by toOrderedUint64s back to a
+// slice of kind k.
+func fromOrderedUint64s(k reflect.Kind, vs []uint64) interface{} {
+
+	switch k {
+	case reflect.Uint64:
+		return vs
+	case reflect.Uint32:
+		rst := make([]uint32, len(vs))
+		for i, x := range vs {
+			rst[i] = uint32(x)
+		}
+		return rst
+	}
+
+	typ, err := intKindType(k)
+	if err != nil {
+		panic(err)
+	}
+
+	sl := reflect.MakeSlice(reflect.SliceOf(typ), len(vs), len(vs))
+	for i, u := range vs {
+		setOrdered(sl.Index(i), u)
+	}
+	return sl.Interface()
+}
+
+// fromOrdered converts an ordered uint64 back to an integer of kind k.
+func fromOrdered(k reflect.Kind, u uint64) interface{} {
+	typ, err := intKindType(k)
+	if err != nil {
+		panic(err)
+	}
+	v := reflect.New(typ).Elem()
+	setOrdered(v, u)
+	return v.Interface()
+}
+
+func toOrdered(k reflect.Kind, v reflect.Value) uint64 {
+	switch k {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(v.Int()) ^ signBit
+	}
+	return v.Uint()
+}
+
+func setOrdered(v reflect.Value, u uint64) {
+	switch v.Kind() {
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(u ^ signBit))
+	default:
+		v.SetUint(u)
+	}
+}
+
+// intKindType returns the type of integer kind k.
+func intKindType(k reflect.Kind) (reflect.Type, error) {
+	switch k {
+	case reflect.Uint8:
+		return reflect.TypeOf(uint8(0)), nil
+	case reflect.Uint16:
+		return reflect.TypeOf(uint16(0)), nil
+	case reflect.Uint32:
+		return reflect.TypeOf(uint32(0)), nil
+	case reflect.Uint64:
+		return reflect.TypeOf(uint64(0)), nil
+	case reflect.Int8:
+		return reflect.TypeOf(int8(0)), nil
+	case reflect.Int16:
+		return reflect.TypeOf(int16(0)), nil
+	case reflect.Int32:
+		return reflect.TypeOf(int32(0)), nil
+	case reflect.Int64:
+		return reflect.TypeOf(int64(0)), nil
+	}
+	return nil, errors.Wrapf(ErrUnknownEltType, "kind: %v", k)
+}
+
+// bitWidth returns the number of bits to store u.
+func bitWidth(u uint64) uint {
+	w := uint(0)
+	for u > 0 {
+		w++
+		u >>= 1
+	}
+	return w
+}
+
+// packedSize returns the number of bytes to store n values of width bits.
+func packedSize(n int, width uint) int {
+	return int((uint64(n)*uint64(width) + 7) / 8)
+}
+
+// packBits appends vals packed with "width" bits each to b.
+// Value i occupies bits [i*width, (i+1)*width), and bit j is the (j%8)-th
+// least significant bit of byte j/8.
+// The higher bits of a value beyond width are discarded.
+func packBits(b []byte, vals []uint64, width uint) []byte {
+
+	start := len(b)
+	size := packedSize(len(vals), width)
+	for i := 0; i < size; i++ {
+		b = append(b, 0)
+	}
+	p := b[start:]
+
+	if width == 0 {
+		return b
+	}
+
+	bit := uint64(0)
+	for _, v := range vals {
+		if width < 64 {
+			v &= 1<<width - 1
+		}
+
+		idx := bit / 8
+		shift := uint(bit % 8)
+		left := width
+		for left > 0 {
+			p[idx] |= byte(v << shift)
+			n := 8 - shift
+			if n > left {
+				n = left
+			}
+			v >>= n
+			left -= n
+			shift = 0
+			idx++
+		}
+		bit += uint64(width)
+	}
+	return b
+}
+
+// unpackBits returns the i-th value of "width" bits in p, packed by packBits.
+func unpackBits(p []byte, i int, width uint) uint64 {
+	if width == 0 {
+		return 0
+	}
+
+	bit := uint64(i) * uint64(width)
+	idx := bit / 8
+	shift := uint(bit % 8)
+
+	var v uint64
+	got := uint(0)
+	for got < width {
+		v |= uint64(p[idx]>>shift) << got
+		got += 8 - shift
+		shift = 0
+		idx++
+	}
+
+	if width < 64 {
+		v &= 1<<width - 1
+	}
+	return v
+}
+
+// intBlocksHeaderSize is the size of element kind and count of integers
+// encoded in blocks.
+const intBlocksHeaderSize = 5
+
+// appendIntBlocks appends integers of kind in blocks of blockSize, every
+// block is encoded by appendBlock.
+// It is the layout shared by FrameOfRef and Linear, integers are
+// little-endian:
+//
+//     kind     uint8       // reflect.Kind of element
+//     n        uint32      // number of elements
+//     offsets  uint32 * (nblocks+1)
+//                          // offset of every block, relative to the end of
+//                          // offsets, and the end of the last block
+//     blocks
+func appendIntBlocks(b []byte, kind reflect.Kind, vals []uint64, blockSize int,
+	appendBlock func([]byte, []uint64) []byte) []byte {
+
+	nblocks := (len(vals) + blockSize - 1) / blockSize
+
+	var blocks []byte
+	offsets := make([]byte, 0, 4*(nblocks+1))
+
+	for i := 0; i < len(vals); i += blockSize {
+		end := i + blockSize
+		if end > len(vals) {
+			end = len(vals)
+		}
+
+		offsets = appendU32(offsets, len(blocks))
+		blocks = appendBlock(blocks, vals[i:end])
+	}
+	offsets = appendU32(offsets, len(blocks))
+
+	b = append(b, byte(kind))
+	b = appendU32(b, len(vals))
+	b = append(b, offsets...)
+	return append(b, blocks...)
+}
+
+// intBlocks is the header of integers encoded by appendIntBlocks.
+type intBlocks struct {
+	kind      reflect.Kind
+	n         int
+	blockSize int
+	nblocks   int
+}
+
+func readIntBlocks(b []byte, blockSize int) intBlocks {
+	n := int(binary.LittleEndian.Uint32(b[1:]))
+	return intBlocks{
+		kind:      reflect.Kind(b[0]),
+		n:         n,
+		blockSize: blockSize,
+		nblocks:   (n + blockSize - 1) / blockSize,
+	}
+}
+
+// start returns the offset of the first block.
+func (h intBlocks) start() int {
+	return intBlocksHeaderSize + 4*(h.nblocks+1)
+}
+
+// encodedSize returns the size of the header, offsets and all blocks.
+func (h intBlocks) encodedSize(b []byte) int {
+	start := h.start()
+	return start + int(binary.LittleEndian.Uint32(b[start-4:]))
+}
+
+// block returns the bytes of the blk-th block.
+func (h intBlocks) block(b []byte, blk int) []byte {
+	off := int(binary.LittleEndian.Uint32(b[intBlocksHeaderSize+4*blk:]))
+	return b[h.start()+off:]
+}
+
+// count returns the number of elements in the blk-th block.
+func (h intBlocks) count(blk int) int {
+	cnt := h.n - blk*h.blockSize
+	if cnt > h.blockSize {
+		cnt = h.blockSize
+	}
+	return cnt
+}
+
+// intBlocksNeededSize implements StreamSizer.NeededSize for integers encoded
+// by appendIntBlocks: the size is known if the header and offsets are
+// complete.
+func intBlocksNeededSize(b []byte, blockSize int) int {
+	if len(b) < intBlocksHeaderSize {
+		return intBlocksHeaderSize
+	}
+	h := readIntBlocks(b, blockSize)
+	if len(b) < h.start() {
+		return h.start()
+	}
+	return h.encodedSize(b)
+}
@@ -0,0 +1,112 @@
+package qcodec
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackBits(t *testing.T) {
+
+	ta := require.New(t)
+
+	cases := []struct {
+		vals  []uint64
+		width uint
+		want  []byte
+	}{
+		{[]uint64{1, 2, 3}, 0, nil},
+		{[]uint64{1, 0, 1, 1}, 1, []byte{0x0d}},
+		{[]uint64{1, 2, 3}, 2, []byte{0x39}},
+		{[]uint64{0x1ff, 0x1}, 9, []byte{0xff, 0x03, 0x00}},
+		{[]uint64{5, 0xff}, 3, []byte{0x3d}},
+	}
+
+	for i, c := range cases {
+		b := packBits(nil, c.vals, c.width)
+		ta.Equal(c.want, b, "%d-th: case: %+v", i+1, c)
+		ta.Equal(packedSize(len(c.vals), c.width), len(b))
+	}
+
+	// prefix is kept
+	ta.Equal([]byte{7, 0x39}, packBits([]byte{7}, []uint64{1, 2, 3}, 2))
+}
+
+func TestPackBitsRandom(t *testing.T) {
+
+	ta := require.New(t)
+
+	rnd := rand.New(rand.NewSource(1))
+
+	for width := uint(0); width <= 64; width++ {
+		vals := make([]uint64, 67)
+		for i := range vals {
+			vals[i] = rnd.Uint64()
+			if width < 64 {
+				vals[i] &= 1<<width - 1
+			}
+		}
+
+		b := packBits(nil, vals, width)
+		ta.Equal(packedSize(len(vals), width), len(b))
+
+		for i, v := range vals {
+			ta.Equal(v, unpackBits(b, i, width), "width: %d, i: %d", width, i)
+		}
+	}
+}
+
+func TestBitWidth(t *testing.T) {
+
+	ta := require.New(t)
+
+	ta.Equal(uint(0), bitWidth(0))
+	ta.Equal(uint(1), bitWidth(1))
+	ta.Equal(uint(2), bitWidth(3))
+	ta.Equal(uint(3), bitWidth(4))
+	ta.Equal(uint(64), bitWidth(math.MaxUint64))
+}
+
+func TestOrderedUint64s(t *testing.T) {
+
+	ta := require.New(t)
+
+	cases := []interface{}{
+		[]uint8{0, 1, 255},
+		[]uint16{0, 1, 65535},
+		[]uint32{0, 1, math.MaxUint32},
+		[]uint64{0, 1, math.MaxUint64},
+		[]int8{-128, -1, 0, 127},
+		[]int16{-32768, -1, 0, 32767},
+		[]int32{math.MinInt32, -1, 0, math.MaxInt32},
+		[]int64{math.MinInt64, -1, 0, math.MaxInt64},
+	}
+
+	for i, c := range cases {
+		vs, k, err := toOrderedUint64s(c)
+		ta.NoError(err)
+		ta.Equal(reflect.TypeOf(c).Elem().Kind(), k)
+
+		// ascending input keeps ascending
+		for j := 1; j < len(vs); j++ {
+			ta.True(vs[j-1] < vs[j], "%d-th: %v", i+1, vs)
+		}
+
+		ta.Equal(c, fromOrderedUint64s(k, vs))
+
+		sl := reflect.ValueOf(c)
+		for j, u := range vs {
+			ta.Equal(sl.Index(j).Interface(), fromOrdered(k, u))
+		}
+	}
+
+	_, _, err := toOrderedUint64s([]int{1})
+	ta.Equal(ErrUnknownEltType, errors.Cause(err))
+
+	_, _, err = toOrderedUint64s(1)
+	ta.Equal(ErrNotSlice, errors.Cause(err))
+}
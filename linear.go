@@ -0,0 +1,196 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// linearBlockSize is the number of values in a Linear block.
+const linearBlockSize = 128
+
+// linearBlockHeaderSize is the size of base, slope, min residual and bit
+// width of a block.
+const linearBlockHeaderSize = 25
+
+// maxLinearSlope limits the slope so that slope*i fits in int64 for every i
+// in a block.
+const maxLinearSlope = 1 << 55
+
+// Linear is a Codec of integer slices that are nearly linear, such as offsets,
+// auto-increment ids or cumulative counts.
+// It accepts any slice GetSliceEltCodec accepts, and decodes to the same type.
+//
+// Values are split into blocks of 128.
+// For every block a line is fitted with least squares, and only the residuals
+// from it are stored, bit-packed in frame-of-reference.
+// A value is predicted as:
+//
+//     base + int64(slope * float64(i))
+//
+// Where i is the index in block.
+// The prediction is only a float64 multiplication and a conversion, without a
+// fused multiply-add, thus it is the same on every platform and the residuals
+// are exact.
+// Get costs the prediction and one bit-unpack.
+//
+// Signed integers have the sign bit flipped as in FrameOfRef.
+//
+// The encoded layout is, integers are little-endian:
+//
+//     kind     uint8       // reflect.Kind of element
+//     n        uint32      // number of elements
+//     offsets  uint32 * (nblocks+1)
+//                          // offset of every block, relative to the end of
+//                          // offsets, and the end of the last block
+//     blocks:
+//       base   uint64      // the first value
+//       slope  float64
+//       min    uint64      // min residual
+//       width  uint8
+//       packed ceil(width*count/8) bytes // residual - min
+type Linear struct{}
+
+// Encode converts a slice of integers to bytes.
+// It panics if d is not a slice of integer of fixed size.
+func (c Linear) Encode(d interface{}) []byte {
+	vals, kind, err := toOrderedUint64s(d)
+	if err != nil {
+		panic(err)
+	}
+
+	return appendIntBlocks(nil, kind, vals, linearBlockSize, appendLinearBlock)
+}
+
+// Decode converts bytes to a slice of integers.
+// It returns number bytes consumed and the slice.
+func (c Linear) Decode(b []byte) (int, interface{}) {
+	h := readIntBlocks(b, linearBlockSize)
+
+	vals := make([]uint64, 0, h.n)
+	for blk := 0; blk < h.nblocks; blk++ {
+		lb := readLinearBlock(h.block(b, blk))
+		for i := 0; i < h.count(blk); i++ {
+			vals = append(vals, lb.get(i))
+		}
+	}
+
+	return h.encodedSize(b), fromOrderedUint64s(h.kind, vals)
+}
+
+// Size returns the size in byte after encoding v.
+func (c Linear) Size(d interface{}) int {
+	return len(c.Encode(d))
+}
+
+// EncodedSize returns size of the encoded slice.
+func (c Linear) EncodedSize(b []byte) int {
+	return readIntBlocks(b, linearBlockSize).encodedSize(b)
+}
+
+// NeededSize returns the encoded size if the header and offsets are complete.
+func (c Linear) NeededSize(b []byte) int {
+	return intBlocksNeededSize(b, linearBlockSize)
+}
+
+// Len returns the number of elements in the encoded slice.
+func (c Linear) Len(b []byte) int {
+	return readIntBlocks(b, linearBlockSize).n
+}
+
+// Get returns the i-th element of the encoded slice, without decoding others.
+// It panics if i is out of range.
+func (c Linear) Get(b []byte, i int) interface{} {
+	h := readIntBlocks(b, linearBlockSize)
+	if i < 0 || i >= h.n {
+		panic(errors.Errorf("index out of range: %d, len: %d", i, h.n))
+	}
+
+	lb := readLinearBlock(h.block(b, i/linearBlockSize))
+	return fromOrdered(h.kind, lb.get(i%linearBlockSize))
+}
+
+// linearBlock is a view of an encoded Linear block.
+type linearBlock struct {
+	base   uint64
+	slope  float64
+	min    uint64
+	width  uint
+	packed []byte
+}
+
+func readLinearBlock(p []byte) *linearBlock {
+	return &linearBlock{
+		base:   binary.LittleEndian.Uint64(p),
+		slope:  math.Float64frombits(binary.LittleEndian.Uint64(p[8:])),
+		min:    binary.LittleEndian.Uint64(p[16:]),
+		width:  uint(p[24]),
+		packed: p[linearBlockHeaderSize:],
+	}
+}
+
+func (lb *linearBlock) get(i int) uint64 {
+	return linearPredict(lb.base, lb.slope, i) + lb.min + unpackBits(lb.packed, i, lb.width)
+}
+
+// linearPredict returns the predicted value of the i-th value.
+// Explicit float64() prevents the compiler from fusing operations.
+func linearPredict(base uint64, slope float64, i int) uint64 {
+	return base + uint64(int64(float64(slope*float64(i))))
+}
+
+// linearFit returns the slope of least squares of vals relative to vals[0].
+func linearFit(vals []uint64) float64 {
+	n := len(vals)
+	if n < 2 {
+		return 0
+	}
+
+	var sx, sy, sxx, sxy float64
+	for i, v := range vals {
+		x := float64(i)
+		y := float64(int64(v - vals[0]))
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+	}
+
+	fn := float64(n)
+	slope := (fn*sxy - sx*sy) / (fn*sxx - sx*sx)
+	if math.IsNaN(slope) || math.Abs(slope) > maxLinearSlope {
+		return 0
+	}
+	return slope
+}
+
+func appendLinearBlock(b []byte, vals []uint64) []byte {
+	base := vals[0]
+	slope := linearFit(vals)
+
+	residuals := make([]uint64, len(vals))
+	minRes := int64(math.MaxInt64)
+	for i, v := range vals {
+		r := int64(v - linearPredict(base, slope, i))
+		residuals[i] = uint64(r)
+		if r < minRes {
+			minRes = r
+		}
+	}
+
+	maxDelta := uint64(0)
+	for i, r := range residuals {
+		residuals[i] = r - uint64(minRes)
+		if residuals[i] > maxDelta {
+			maxDelta = residuals[i]
+		}
+	}
+	width := bitWidth(maxDelta)
+
+	b = appendU64(b, base)
+	b = appendU64(b, math.Float64bits(slope))
+	b = appendU64(b, uint64(minRes))
+	b = append(b, byte(width))
+	return packBits(b, residuals, width)
+}
@@ -0,0 +1,94 @@
+package qcodec
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinear(t *testing.T) {
+
+	ta := require.New(t)
+
+	rnd := rand.New(rand.NewSource(1))
+
+	offsets := make([]uint64, 1000)
+	for i := range offsets {
+		// records of about 100 bytes
+		offsets[i] = uint64(i*100 + rnd.Intn(8))
+	}
+
+	random := make([]int64, 300)
+	for i := range random {
+		random[i] = int64(rnd.Uint64())
+	}
+
+	desc := make([]int32, 200)
+	for i := range desc {
+		desc[i] = int32(1000 - i*37)
+	}
+
+	cases := []interface{}{
+		[]uint32{},
+		[]uint32{5},
+		[]uint32{5, 3},
+		offsets,
+		random,
+		desc,
+		[]uint64{0, math.MaxUint64, 0, math.MaxUint64},
+		[]int8{-128, 127, -128},
+		[]uint16{1, 2, 3, 4, 5, 6},
+	}
+
+	c := Linear{}
+
+	for i, input := range cases {
+		b := c.Encode(input)
+		ta.Equal(len(b), c.Size(input), "%d-th", i+1)
+		ta.Equal(len(b), c.EncodedSize(b))
+		ta.Equal(len(b), c.NeededSize(b))
+
+		n, v := c.Decode(append(b, 1, 2))
+		ta.Equal(len(b), n)
+		ta.Equal(input, v, "%d-th", i+1)
+
+		checkGet(ta, input, c.Len(b), func(i int) interface{} { return c.Get(b, i) })
+		testPanic(t, func() { c.Get(b, c.Len(b)) }, "out of range")
+	}
+
+	// exact lines have no residual
+	line := make([]uint64, 256)
+	for i := range line {
+		line[i] = 1<<40 + uint64(i)*4096
+	}
+	b := c.Encode(line)
+	ta.Equal(intBlocksHeaderSize+4*3+2*linearBlockHeaderSize, len(b))
+
+	// residuals of offsets take 3 bits, smaller than delta encoding
+	b = c.Encode(offsets)
+	ta.True(len(b) < len(DeltaSeq{}.Encode(offsets))/2, "size: %d", len(b))
+	ta.True(len(b) < len(FrameOfRef{}.Encode(offsets))/2, "size: %d", len(b))
+
+	ta.Equal(intBlocksHeaderSize, c.NeededSize(nil))
+	ta.Equal(intBlocksHeaderSize+4*9, c.NeededSize(b[:intBlocksHeaderSize]))
+
+	testPanic(t, func() { c.Encode([]int{1}) }, "int is not fixed size")
+}
+
+func TestLinearPredict(t *testing.T) {
+
+	ta := require.New(t)
+
+	ta.Equal(uint64(10), linearPredict(10, 0, 100))
+	ta.Equal(uint64(10+250), linearPredict(10, 2.5, 100))
+	ta.Equal(uint64(10-3), linearPredict(10, -1.5, 2))
+
+	ta.Equal(0.0, linearFit([]uint64{7}))
+	ta.Equal(2.0, linearFit([]uint64{1, 3, 5, 7}))
+	ta.Equal(-1.0, linearFit([]uint64{3, 2, 1}))
+
+	// too steep
+	ta.Equal(0.0, linearFit([]uint64{0, math.MaxUint64 / 2}))
+}
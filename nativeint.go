@@ -51,3 +51,10 @@ func (c Int) Size(d interface{}) int {
 func (c Int) EncodedSize(b []byte) int {
 	return bits.UintSize / 8
 }
+
+// Compare decodes and compares two encoded int.
+func (c Int) Compare(a, b []byte) int {
+	_, x := c.Decode(a)
+	_, y := c.Decode(b)
+	return cmpInt64(int64(x.(int)), int64(y.(int)))
+}
@@ -0,0 +1,411 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/pkg/errors"
+)
+
+// rsHeaderSize is the size of the header of ReedSolomonCodec: 3 copies of the
+// inner encoding size.
+const rsHeaderSize = 12
+
+// rsShardSumSize is the size of the CRC32C checksum of every shard.
+const rsShardSumSize = 4
+
+// ErrInvalidShards indicates the number of data or parity shards is invalid.
+var ErrInvalidShards = errors.New("invalid number of shards")
+
+// ReedSolomonCodec protects the encoding of an inner Codec with Reed-Solomon
+// erasure code over GF(2^8), so that corrupted data is not only detected but
+// repaired.
+//
+// The inner encoding is split into "dataShards" shards of equal size, padded
+// with zeros, and "parityShards" parity shards are computed from them.
+// Every shard has a CRC32C checksum to locate corruption.
+// Up to "parityShards" corrupted shards, with any number of corrupted bytes in
+// them, are repaired on decoding.
+//
+// An encoded value is in the following layout, integers are little-endian
+// uint32:
+//
+//     size, size, size        // 3 copies of the inner encoding size
+//     shard, crc32c(shard)    // dataShards + parityShards times
+//
+// The size is determined by majority so that one corrupted copy is tolerated.
+type ReedSolomonCodec struct {
+	inner        Codec
+	dataShards   int
+	parityShards int
+	// parity[i][j] is the coefficient of data shard j for parity shard i.
+	parity [][]byte
+}
+
+// ReedSolomon creates a *ReedSolomonCodec with "dataShards" data shards and
+// "parityShards" parity shards.
+// The total number of shards must not exceed 256.
+func ReedSolomon(inner Codec, dataShards, parityShards int) (*ReedSolomonCodec, error) {
+	if dataShards < 1 || parityShards < 1 || dataShards+parityShards > 256 {
+		return nil, errors.Wrapf(ErrInvalidShards, "data: %d, parity: %d", dataShards, parityShards)
+	}
+
+	// Cauchy matrix: every square sub-matrix of [I; parity] is invertible.
+	parity := make([][]byte, parityShards)
+	for i := range parity {
+		parity[i] = make([]byte, dataShards)
+		for j := range parity[i] {
+			parity[i][j] = gfInv(byte(dataShards+i) ^ byte(j))
+		}
+	}
+
+	return &ReedSolomonCodec{
+		inner:        inner,
+		dataShards:   dataShards,
+		parityShards: parityShards,
+		parity:       parity,
+	}, nil
+}
+
+// Encode encodes d with the inner Codec and appends parity shards.
+func (c *ReedSolomonCodec) Encode(d interface{}) []byte {
+	data := c.inner.Encode(d)
+	shardSize := c.shardSize(len(data))
+
+	rst := make([]byte, 0, c.size(len(data)))
+	for i := 0; i < 3; i++ {
+		rst = appendU32(rst, len(data))
+	}
+
+	shards := make([][]byte, c.dataShards+c.parityShards)
+	for i := 0; i < c.dataShards; i++ {
+		shards[i] = make([]byte, shardSize)
+		if i*shardSize < len(data) {
+			copy(shards[i], data[i*shardSize:])
+		}
+	}
+
+	for i := 0; i < c.parityShards; i++ {
+		p := make([]byte, shardSize)
+		for j := 0; j < c.dataShards; j++ {
+			gfMulAdd(p, shards[j], c.parity[i][j])
+		}
+		shards[c.dataShards+i] = p
+	}
+
+	for _, s := range shards {
+		rst = append(rst, s...)
+		rst = appendU32(rst, int(crc32.Checksum(s, crc32cTable)))
+	}
+	return rst
+}
+
+// Decode repairs corrupted shards if there are any and decodes a value.
+// It returns number bytes consumed and the value decoded by the inner Codec.
+// It panics if there are too many corrupted shards to repair.
+func (c *ReedSolomonCodec) Decode(b []byte) (int, interface{}) {
+	n, v, err := c.DecodeChecked(b)
+	if err != nil {
+		panic(err)
+	}
+	return n, v
+}
+
+// DecodeChecked is the same as Decode except it returns an error wrapping
+// ErrCorrupted if there are too many corrupted shards.
+func (c *ReedSolomonCodec) DecodeChecked(b []byte) (int, interface{}, error) {
+	n, v, _, err := c.DecodeCorrected(b)
+	return n, v, err
+}
+
+// DecodeCorrected is the same as DecodeChecked except it also returns the
+// number of corrections made: the number of repaired shards plus the number of
+// corrupted copies of the size header.
+func (c *ReedSolomonCodec) DecodeCorrected(b []byte) (int, interface{}, int, error) {
+
+	n, data, corrected, err := c.repair(b)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+
+	_, v, err := decodeChecked(c.inner, data)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	return n, v, corrected, nil
+}
+
+// repair returns the number of bytes consumed, the inner encoding with
+// corrupted shards repaired and the number of corrections made.
+func (c *ReedSolomonCodec) repair(b []byte) (int, []byte, int, error) {
+
+	dataSize, corrected, err := c.readHeader(b)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+
+	n := c.size(dataSize)
+	if len(b) < n {
+		return 0, nil, 0, errors.Wrapf(ErrShortBuffer, "need: %d, got: %d", n, len(b))
+	}
+
+	shardSize := c.shardSize(dataSize)
+	total := c.dataShards + c.parityShards
+
+	shards := make([][]byte, total)
+	valid := make([]bool, total)
+	nvalid := 0
+	dataValid := true
+	for i := range shards {
+		start := rsHeaderSize + i*(shardSize+rsShardSumSize)
+		shards[i] = b[start : start+shardSize]
+		sum := binary.LittleEndian.Uint32(b[start+shardSize:])
+
+		valid[i] = crc32.Checksum(shards[i], crc32cTable) == sum
+		if valid[i] {
+			nvalid++
+		} else {
+			corrected++
+			if i < c.dataShards {
+				dataValid = false
+			}
+		}
+	}
+
+	if nvalid < c.dataShards {
+		return 0, nil, 0, errors.Wrapf(ErrCorrupted, "corrupted shards: %d, parity shards: %d",
+			total-nvalid, c.parityShards)
+	}
+
+	data := make([]byte, 0, shardSize*c.dataShards)
+	if dataValid {
+		for _, s := range shards[:c.dataShards] {
+			data = append(data, s...)
+		}
+	} else {
+		for _, s := range c.reconstruct(shards, valid, shardSize) {
+			data = append(data, s...)
+		}
+	}
+	data = data[:dataSize]
+
+	want, err := neededSizeChecked(c.inner, data)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	if want != len(data) {
+		return 0, nil, 0, errors.Wrapf(ErrCorrupted, "inner size: %d, want: %d", len(data), want)
+	}
+
+	return n, data, corrected, nil
+}
+
+// Size returns the size in byte after encoding v.
+func (c *ReedSolomonCodec) Size(d interface{}) int {
+	return c.size(c.inner.Size(d))
+}
+
+// EncodedSize returns the size of the encoded value by reading the header.
+// It panics if two or more copies of the size in header are corrupted.
+func (c *ReedSolomonCodec) EncodedSize(b []byte) int {
+	dataSize, _, err := c.readHeader(b)
+	if err != nil {
+		panic(err)
+	}
+	return c.size(dataSize)
+}
+
+// NeededSize returns the encoded size if the header is complete.
+// It panics if two or more copies of the size in header are corrupted.
+func (c *ReedSolomonCodec) NeededSize(b []byte) int {
+	return mustNeededSize(c.NeededSizeChecked(b))
+}
+
+// NeededSizeChecked is the same as NeededSize except it returns an error
+// wrapping ErrCorrupted if two or more copies of the size are corrupted.
+func (c *ReedSolomonCodec) NeededSizeChecked(b []byte) (int, error) {
+	if len(b) < rsHeaderSize {
+		return rsHeaderSize, nil
+	}
+	dataSize, _, err := c.readHeader(b)
+	if err != nil {
+		return 0, err
+	}
+	return c.size(dataSize), nil
+}
+
+// Compare repairs two values if needed and compares their inner encodings with
+// the inner Codec.
+// It panics if there are too many corrupted shards to repair.
+func (c *ReedSolomonCodec) Compare(a, b []byte) int {
+	_, x, _, err := c.repair(a)
+	if err != nil {
+		panic(err)
+	}
+	_, y, _, err := c.repair(b)
+	if err != nil {
+		panic(err)
+	}
+	return CompareEncoded(c.inner, x, y)
+}
+
+// readHeader returns the inner encoding size and the number of corrupted
+// copies of it.
+func (c *ReedSolomonCodec) readHeader(b []byte) (int, int, error) {
+	if len(b) < rsHeaderSize {
+		return 0, 0, errors.Wrapf(ErrShortBuffer, "need: %d, got: %d", rsHeaderSize, len(b))
+	}
+
+	x := binary.LittleEndian.Uint32(b)
+	y := binary.LittleEndian.Uint32(b[4:])
+	z := binary.LittleEndian.Uint32(b[8:])
+
+	switch {
+	case x == y && y == z:
+		return int(x), 0, nil
+	case x == y || x == z:
+		return int(x), 1, nil
+	case y == z:
+		return int(y), 1, nil
+	}
+	return 0, 0, errors.Wrapf(ErrCorrupted, "size header: %d, %d, %d", x, y, z)
+}
+
+func (c *ReedSolomonCodec) shardSize(dataSize int) int {
+	return (dataSize + c.dataShards - 1) / c.dataShards
+}
+
+func (c *ReedSolomonCodec) size(dataSize int) int {
+	total := c.dataShards + c.parityShards
+	return rsHeaderSize + total*(c.shardSize(dataSize)+rsShardSumSize)
+}
+
+// reconstruct returns the data shards from the first "dataShards" valid
+// shards.
+func (c *ReedSolomonCodec) reconstruct(shards [][]byte, valid []bool, shardSize int) [][]byte {
+
+	k := c.dataShards
+
+	// rows of the encoding matrix of the chosen shards
+	m := make([][]byte, 0, k)
+	chosen := make([][]byte, 0, k)
+	for i := range shards {
+		if !valid[i] {
+			continue
+		}
+
+		if i < k {
+			row := make([]byte, k)
+			row[i] = 1
+			m = append(m, row)
+		} else {
+			m = append(m, append([]byte{}, c.parity[i-k]...))
+		}
+		chosen = append(chosen, shards[i])
+
+		if len(m) == k {
+			break
+		}
+	}
+
+	inv := gfInvertMatrix(m)
+
+	data := make([][]byte, k)
+	for i := range data {
+		if valid[i] {
+			data[i] = shards[i]
+			continue
+		}
+		data[i] = make([]byte, shardSize)
+		for j, s := range chosen {
+			gfMulAdd(data[i], s, inv[i][j])
+		}
+	}
+	return data
+}
+
+// GF(2^8) with the polynomial x^8 + x^4 + x^3 + x^2 + 1.
+var (
+	gfExp [512]byte
+	gfLog [256]byte
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfLog[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	for i := 255; i < len(gfExp); i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfInv(a byte) byte {
+	if a == 0 {
+		panic("GF(2^8): inverse of 0")
+	}
+	return gfExp[255-int(gfLog[a])]
+}
+
+// gfMulAdd adds c*src to dst.
+func gfMulAdd(dst, src []byte, c byte) {
+	if c == 0 {
+		return
+	}
+	for i, s := range src {
+		dst[i] ^= gfMul(c, s)
+	}
+}
+
+// gfInvertMatrix inverts a square matrix with Gauss-Jordan elimination.
+// m is modified.
+// It panics if m is singular.
+func gfInvertMatrix(m [][]byte) [][]byte {
+	n := len(m)
+
+	inv := make([][]byte, n)
+	for i := range inv {
+		inv[i] = make([]byte, n)
+		inv[i][i] = 1
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for pivot < n && m[pivot][col] == 0 {
+			pivot++
+		}
+		if pivot == n {
+			panic("GF(2^8): singular matrix")
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+
+		c := gfInv(m[col][col])
+		for j := 0; j < n; j++ {
+			m[col][j] = gfMul(m[col][j], c)
+			inv[col][j] = gfMul(inv[col][j], c)
+		}
+
+		for i := 0; i < n; i++ {
+			if i == col || m[i][col] == 0 {
+				continue
+			}
+			f := m[i][col]
+			gfMulAdd(m[i], m[col], f)
+			gfMulAdd(inv[i], inv[col], f)
+		}
+	}
+	return inv
+}
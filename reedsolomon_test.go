@@ -0,0 +1,180 @@
+package qcodec
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGF(t *testing.T) {
+
+	ta := require.New(t)
+
+	for a := 1; a < 256; a++ {
+		ta.Equal(byte(1), gfMul(byte(a), gfInv(byte(a))), "a: %d", a)
+		ta.Equal(byte(0), gfMul(byte(a), 0))
+		ta.Equal(byte(a), gfMul(byte(a), 1))
+	}
+
+	testPanic(t, func() { gfInv(0) }, "inverse of 0")
+	testPanic(t, func() { gfInvertMatrix([][]byte{{1, 1}, {1, 1}}) }, "singular")
+}
+
+func TestReedSolomon(t *testing.T) {
+
+	ta := require.New(t)
+
+	long := strings.Repeat("0123456789", 30)
+
+	cases := []struct {
+		inner        Codec
+		data, parity int
+		input        interface{}
+	}{
+		{String16{}, 1, 1, ""},
+		{String16{}, 4, 2, "hello"},
+		{String16{}, 10, 4, long},
+		{String16{}, 3, 3, long},
+		{U64{}, 2, 1, uint64(0x0102030405060708)},
+		{String16{}, 200, 56, long},
+	}
+
+	for i, c := range cases {
+		m, err := ReedSolomon(c.inner, c.data, c.parity)
+		ta.NoError(err)
+
+		b := m.Encode(c.input)
+		ta.Equal(len(b), m.Size(c.input), "%d-th: case: %+v", i+1, c)
+		ta.Equal(len(b), m.EncodedSize(b))
+		ta.Equal(len(b), m.NeededSize(b))
+		ta.Equal(rsHeaderSize, m.NeededSize(b[:3]))
+
+		n, v, corrected, err := m.DecodeCorrected(append(b, 1, 2))
+		ta.NoError(err)
+		ta.Equal(len(b), n)
+		ta.Equal(c.input, v)
+		ta.Equal(0, corrected)
+
+		ta.Equal(0, m.Compare(b, b))
+
+		shardSize := m.shardSize(c.inner.Size(c.input))
+		total := c.data + c.parity
+
+		rnd := rand.New(rand.NewSource(int64(i)))
+		for round := 0; round < 20; round++ {
+
+			// corrupt up to "parity" shards, and one header copy
+			bad := append([]byte{}, b...)
+			nbad := 1 + rnd.Intn(c.parity)
+			for _, s := range rnd.Perm(total)[:nbad] {
+				start := rsHeaderSize + s*(shardSize+rsShardSumSize)
+				for j := 0; j < 1+rnd.Intn(shardSize+rsShardSumSize); j++ {
+					bad[start+rnd.Intn(shardSize+rsShardSumSize)] ^= byte(1 + rnd.Intn(255))
+				}
+			}
+			bad[rnd.Intn(rsHeaderSize)] ^= 0x40
+
+			n, v, corrected, err := m.DecodeCorrected(bad)
+			ta.NoError(err, "%d-th: round: %d", i+1, round)
+			ta.Equal(len(b), n)
+			ta.Equal(c.input, v)
+			ta.True(corrected >= 2 && corrected <= nbad+1, "%d-th: corrected: %d, bad: %d", i+1, corrected, nbad)
+		}
+
+		// too many corrupted shards
+		bad := append([]byte{}, b...)
+		for s := 0; s <= c.parity; s++ {
+			bad[rsHeaderSize+s*(shardSize+rsShardSumSize)+shardSize] ^= 1
+		}
+		_, _, err = m.DecodeChecked(bad)
+		ta.Equal(ErrCorrupted, errors.Cause(err), "%d-th", i+1)
+		testPanic(t, func() { m.Decode(bad) }, "too many corrupted shards")
+	}
+}
+
+func TestReedSolomonError(t *testing.T) {
+
+	ta := require.New(t)
+
+	for _, c := range [][2]int{{0, 1}, {1, 0}, {200, 57}, {-1, 3}} {
+		_, err := ReedSolomon(U8{}, c[0], c[1])
+		ta.Equal(ErrInvalidShards, errors.Cause(err), "case: %v", c)
+	}
+
+	m, err := ReedSolomon(String16{}, 2, 2)
+	ta.NoError(err)
+
+	b := m.Encode("abc")
+
+	// 2 of 3 size copies corrupted
+	bad := append([]byte{}, b...)
+	bad[0] ^= 1
+	bad[4] ^= 2
+	_, _, err = m.DecodeChecked(bad)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+	testPanic(t, func() { m.EncodedSize(bad) }, "corrupted header")
+	testPanic(t, func() { m.NeededSize(bad) }, "corrupted header")
+
+	_, err = m.NeededSizeChecked(bad)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+	_, _, err = NewDecoder(m).Feed(bad)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+	_, err = NewReader(bad).Read(m)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+
+	_, _, err = m.DecodeChecked(b[:len(b)-1])
+	ta.Equal(ErrShortBuffer, errors.Cause(err))
+	_, _, err = m.DecodeChecked(b[:5])
+	ta.Equal(ErrShortBuffer, errors.Cause(err))
+}
+
+func TestReedSolomonCompose(t *testing.T) {
+
+	ta := require.New(t)
+
+	m, err := ReedSolomon(U32{}, 2, 2)
+	ta.NoError(err)
+
+	n, ok := fixedSize(m)
+	ta.True(ok)
+	ta.Equal(m.Size(uint32(0)), n)
+
+	a, err := NewArray(m, []uint32{1, 2, 3})
+	ta.NoError(err)
+	ta.Equal(uint32(2), a.Get(1))
+
+	// corrupted values are repaired through Reader
+	ms, err := ReedSolomon(String16{}, 3, 1)
+	ta.NoError(err)
+	b := NewBuilder(0).Append(ms, "foo").Append(ms, "bar").Bytes()
+	b[rsHeaderSize+1] ^= 0xff
+
+	r := NewReader(b)
+	v, err := r.Read(ms)
+	ta.NoError(err)
+	ta.Equal("foo", v)
+	v, err = r.Read(ms)
+	ta.NoError(err)
+	ta.Equal("bar", v)
+
+	// compared by the inner Codec, after repair
+	tc, err := NewTupleCodec("", Desc(int64(0)))
+	ta.NoError(err)
+	mt, err := ReedSolomon(tc, 2, 1)
+	ta.NoError(err)
+
+	x := mt.Encode(Tuple{"a", int64(1)})
+	y := mt.Encode(Tuple{"a", int64(2)})
+	ta.Equal(1, mt.Compare(x, y))
+	ta.Equal(-1, mt.Compare(y, x))
+
+	x[rsHeaderSize] ^= 0xff
+	ta.Equal(1, mt.Compare(x, y))
+	ta.Equal(0, mt.Compare(x, mt.Encode(Tuple{"a", int64(1)})))
+
+	x[rsHeaderSize+mt.shardSize(tc.Size(Tuple{"a", int64(1)}))+rsShardSumSize] ^= 0xff
+	testPanic(t, func() { mt.Compare(x, y) }, "too many corrupted shards")
+}
@@ -0,0 +1,425 @@
+package qcodec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// hybridMinRun is the minimal length of a run that HybridRLECodec stores as a
+// run group.
+const hybridMinRun = 8
+
+// hybridMaxLiterals is the max number of values in a literal group of
+// HybridRLECodec.
+const hybridMaxLiterals = 128
+
+// hybridRunGroup is the group header of a run group of HybridRLECodec.
+// A literal group has a header of bit width + 1.
+const hybridRunGroup = 0
+
+// RLECodec is a Codec of slices of mostly repeated values, such as flags or
+// status, that stores every run of equal values once with its length.
+// Values are encoded with a fixed size element Codec, and compared by the
+// encoded bytes.
+// It accepts a slice of values the element Codec accepts, and decodes to a
+// slice of the type the element Codec decodes to.
+//
+// Get is a binary search on the cumulative run ends, without decoding.
+//
+// The encoded layout is, integers are little-endian:
+//
+//     nruns   uint32
+//     ends    uint32 * nruns // index after every run
+//     values  nruns * element size
+type RLECodec struct {
+	elt     Codec
+	eltSize int
+	eltType reflect.Type
+}
+
+// RLE creates a *RLECodec with a fixed size element Codec.
+func RLE(elt Codec) (*RLECodec, error) {
+	size, typ, err := rleElt(elt)
+	if err != nil {
+		return nil, err
+	}
+	return &RLECodec{elt: elt, eltSize: size, eltType: typ}, nil
+}
+
+// Encode converts a slice to bytes.
+// It panics if d is not a slice.
+func (c *RLECodec) Encode(d interface{}) []byte {
+	vals := encodeElts(c.elt, d)
+
+	var ends, values []byte
+	nruns := 0
+	for i, v := range vals {
+		if i > 0 && bytes.Equal(vals[i-1], v) {
+			continue
+		}
+		if i > 0 {
+			ends = appendU32(ends, i)
+		}
+		values = append(values, v...)
+		nruns++
+	}
+	if nruns > 0 {
+		ends = appendU32(ends, len(vals))
+	}
+
+	rst := make([]byte, 0, 4+len(ends)+len(values))
+	rst = appendU32(rst, nruns)
+	rst = append(rst, ends...)
+	return append(rst, values...)
+}
+
+// Decode converts bytes to a slice.
+// It returns number bytes consumed and the slice.
+func (c *RLECodec) Decode(b []byte) (int, interface{}) {
+	nruns := int(binary.LittleEndian.Uint32(b))
+
+	var vals []interface{}
+	start := 0
+	for r := 0; r < nruns; r++ {
+		end := int(binary.LittleEndian.Uint32(b[4+r*4:]))
+		_, v := c.elt.Decode(c.value(b, nruns, r))
+		for ; start < end; start++ {
+			vals = append(vals, v)
+		}
+	}
+	return c.EncodedSize(b), makeEltSlice(c.eltType, vals)
+}
+
+// Size returns the size in byte after encoding v.
+func (c *RLECodec) Size(d interface{}) int {
+	return len(c.Encode(d))
+}
+
+// EncodedSize returns size of the encoded slice.
+func (c *RLECodec) EncodedSize(b []byte) int {
+	nruns := int(binary.LittleEndian.Uint32(b))
+	return 4 + nruns*(4+c.eltSize)
+}
+
+// NeededSize returns the encoded size if the header is complete.
+func (c *RLECodec) NeededSize(b []byte) int {
+	if len(b) < 4 {
+		return 4
+	}
+	return c.EncodedSize(b)
+}
+
+// Len returns the number of values.
+func (c *RLECodec) Len(b []byte) int {
+	nruns := int(binary.LittleEndian.Uint32(b))
+	if nruns == 0 {
+		return 0
+	}
+	return int(binary.LittleEndian.Uint32(b[4+(nruns-1)*4:]))
+}
+
+// Runs returns the number of runs.
+func (c *RLECodec) Runs(b []byte) int {
+	return int(binary.LittleEndian.Uint32(b))
+}
+
+// Get returns the i-th value.
+// It panics if i is out of range.
+func (c *RLECodec) Get(b []byte, i int) interface{} {
+	n := c.Len(b)
+	if i < 0 || i >= n {
+		panic(errors.Errorf("index out of range: %d, len: %d", i, n))
+	}
+
+	nruns := int(binary.LittleEndian.Uint32(b))
+	r := sort.Search(nruns, func(r int) bool {
+		return int(binary.LittleEndian.Uint32(b[4+r*4:])) > i
+	})
+	_, v := c.elt.Decode(c.value(b, nruns, r))
+	return v
+}
+
+func (c *RLECodec) value(b []byte, nruns, r int) []byte {
+	off := 4 + nruns*4 + r*c.eltSize
+	return b[off : off+c.eltSize]
+}
+
+// HybridRLECodec is a Codec like RLECodec for slices of mixed runs and
+// distinct values.
+// Values are split into groups: a run of at least 8 equal values is a run
+// group, and other values are in literal groups of at most 128 values, which
+// are bit-packed at the width of the largest one.
+// The element Codec must be fixed size of at most 8 bytes, and the encoded
+// bytes of a value are treated as a little-endian integer for bit-packing,
+// which is compact for small integers of U8, U16, U32 or U64.
+//
+// Get is a binary search on the cumulative group ends, and decodes only one
+// value.
+//
+// The encoded layout is, integers are little-endian:
+//
+//     ngroups  uint32
+//     groups:  ngroups times
+//       end    uint32 // index after the group
+//       offset uint32 // offset of the group in data
+//     size     uint32 // size of data
+//     data:
+//       run group:     0, value
+//       literal group: width+1, ceil(count*width/8) bytes
+type HybridRLECodec struct {
+	elt     Codec
+	eltSize int
+	eltType reflect.Type
+}
+
+// HybridRLE creates a *HybridRLECodec with a fixed size element Codec of at
+// most 8 bytes.
+func HybridRLE(elt Codec) (*HybridRLECodec, error) {
+	size, typ, err := rleElt(elt)
+	if err != nil {
+		return nil, err
+	}
+	if size > 8 {
+		return nil, errors.Wrapf(ErrNotFixedSize, "element size: %d, want at most 8", size)
+	}
+	return &HybridRLECodec{elt: elt, eltSize: size, eltType: typ}, nil
+}
+
+// Encode converts a slice to bytes.
+// It panics if d is not a slice.
+func (c *HybridRLECodec) Encode(d interface{}) []byte {
+	vals := encodeElts(c.elt, d)
+
+	var groups, data []byte
+	var lits []uint64
+
+	flush := func(end int) {
+		if len(lits) == 0 {
+			return
+		}
+		width := uint(0)
+		for _, x := range lits {
+			if w := bitWidth(x); w > width {
+				width = w
+			}
+		}
+		groups = appendU32(groups, end)
+		groups = appendU32(groups, len(data))
+		data = append(data, byte(width+1))
+		data = packBits(data, lits, width)
+		lits = lits[:0]
+	}
+
+	for i := 0; i < len(vals); {
+		j := i + 1
+		for j < len(vals) && bytes.Equal(vals[i], vals[j]) {
+			j++
+		}
+
+		if j-i >= hybridMinRun {
+			flush(i)
+			groups = appendU32(groups, j)
+			groups = appendU32(groups, len(data))
+			data = append(data, hybridRunGroup)
+			data = append(data, vals[i]...)
+			i = j
+			continue
+		}
+
+		for ; i < j; i++ {
+			lits = append(lits, leUint(vals[i]))
+			if len(lits) == hybridMaxLiterals {
+				flush(i + 1)
+			}
+		}
+	}
+	flush(len(vals))
+
+	ngroups := len(groups) / 8
+	rst := make([]byte, 0, 4+len(groups)+4+len(data))
+	rst = appendU32(rst, ngroups)
+	rst = append(rst, groups...)
+	rst = appendU32(rst, len(data))
+	return append(rst, data...)
+}
+
+// Decode converts bytes to a slice.
+// It returns number bytes consumed and the slice.
+func (c *HybridRLECodec) Decode(b []byte) (int, interface{}) {
+	ngroups := int(binary.LittleEndian.Uint32(b))
+
+	var vals []interface{}
+	start := 0
+	for g := 0; g < ngroups; g++ {
+		end, p := c.group(b, g)
+		if p[0] == hybridRunGroup {
+			_, v := c.elt.Decode(p[1 : 1+c.eltSize])
+			for ; start < end; start++ {
+				vals = append(vals, v)
+			}
+			continue
+		}
+
+		width := uint(p[0] - 1)
+		for k := 0; start < end; start++ {
+			vals = append(vals, c.decodeUint(unpackBits(p[1:], k, width)))
+			k++
+		}
+	}
+	return c.EncodedSize(b), makeEltSlice(c.eltType, vals)
+}
+
+// Size returns the size in byte after encoding v.
+func (c *HybridRLECodec) Size(d interface{}) int {
+	return len(c.Encode(d))
+}
+
+// EncodedSize returns size of the encoded slice.
+func (c *HybridRLECodec) EncodedSize(b []byte) int {
+	ngroups := int(binary.LittleEndian.Uint32(b))
+	dataStart := 4 + ngroups*8 + 4
+	return dataStart + int(binary.LittleEndian.Uint32(b[dataStart-4:]))
+}
+
+// NeededSize returns the encoded size if the header and groups are complete.
+func (c *HybridRLECodec) NeededSize(b []byte) int {
+	if len(b) < 4 {
+		return 4
+	}
+	dataStart := 4 + int(binary.LittleEndian.Uint32(b))*8 + 4
+	if len(b) < dataStart {
+		return dataStart
+	}
+	return c.EncodedSize(b)
+}
+
+// Len returns the number of values.
+func (c *HybridRLECodec) Len(b []byte) int {
+	ngroups := int(binary.LittleEndian.Uint32(b))
+	if ngroups == 0 {
+		return 0
+	}
+	return int(binary.LittleEndian.Uint32(b[4+(ngroups-1)*8:]))
+}
+
+// Get returns the i-th value.
+// It panics if i is out of range.
+func (c *HybridRLECodec) Get(b []byte, i int) interface{} {
+	n := c.Len(b)
+	if i < 0 || i >= n {
+		panic(errors.Errorf("index out of range: %d, len: %d", i, n))
+	}
+
+	ngroups := int(binary.LittleEndian.Uint32(b))
+	g := sort.Search(ngroups, func(g int) bool {
+		return int(binary.LittleEndian.Uint32(b[4+g*8:])) > i
+	})
+
+	_, p := c.group(b, g)
+	if p[0] == hybridRunGroup {
+		_, v := c.elt.Decode(p[1 : 1+c.eltSize])
+		return v
+	}
+
+	start := 0
+	if g > 0 {
+		start, _ = c.group(b, g-1)
+	}
+	return c.decodeUint(unpackBits(p[1:], i-start, uint(p[0]-1)))
+}
+
+// group returns the end of the g-th group and the data from it.
+func (c *HybridRLECodec) group(b []byte, g int) (int, []byte) {
+	ngroups := int(binary.LittleEndian.Uint32(b))
+	dataStart := 4 + ngroups*8 + 4
+
+	end := int(binary.LittleEndian.Uint32(b[4+g*8:]))
+	off := int(binary.LittleEndian.Uint32(b[4+g*8+4:]))
+	return end, b[dataStart+off:]
+}
+
+func (c *HybridRLECodec) decodeUint(u uint64) interface{} {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], u)
+	_, v := c.elt.Decode(buf[:c.eltSize])
+	return v
+}
+
+// rleElt returns the size of a fixed size element Codec and the type it
+// decodes to, which is nil if it decodes to nil, such as Dummy.
+func rleElt(elt Codec) (int, reflect.Type, error) {
+	size, ok := fixedSize(elt)
+	if !ok {
+		return 0, nil, errors.Wrapf(ErrNotFixedSize, "codec: %T", elt)
+	}
+
+	typ, err := decodedType(elt)
+	if err != nil {
+		return 0, nil, err
+	}
+	return size, typ, nil
+}
+
+// decodedType returns the type a fixed size Codec decodes to, by decoding
+// zero bytes.
+// Wrapping codecs, that reject zero bytes, are asked for the type of the inner
+// Codec.
+func decodedType(c Codec) (reflect.Type, error) {
+	switch t := c.(type) {
+	case *ChecksumCodec:
+		return decodedType(t.inner)
+	case *SignedCodec:
+		return decodedType(t.inner)
+	case *ReedSolomonCodec:
+		return decodedType(t.inner)
+	}
+
+	size, _ := fixedSize(c)
+	_, zero, err := decodeChecked(c, make([]byte, size))
+	if err != nil {
+		return nil, errors.Wrapf(err, "codec: %T", c)
+	}
+	return reflect.TypeOf(zero), nil
+}
+
+// encodeElts encodes every element of slice d with elt.
+func encodeElts(elt Codec, d interface{}) [][]byte {
+	sl := reflect.ValueOf(d)
+	if sl.Kind() != reflect.Slice {
+		panic(errors.Wrapf(ErrNotSlice, "type: %T", d))
+	}
+
+	rst := make([][]byte, sl.Len())
+	for i := range rst {
+		rst[i] = elt.Encode(sl.Index(i).Interface())
+	}
+	return rst
+}
+
+// makeEltSlice returns a slice of typ of vals, or a []interface{} if typ is
+// nil.
+func makeEltSlice(typ reflect.Type, vals []interface{}) interface{} {
+	if typ == nil {
+		if vals == nil {
+			vals = []interface{}{}
+		}
+		return vals
+	}
+
+	sl := reflect.MakeSlice(reflect.SliceOf(typ), len(vals), len(vals))
+	for i, v := range vals {
+		sl.Index(i).Set(reflect.ValueOf(v))
+	}
+	return sl.Interface()
+}
+
+// leUint returns b of at most 8 bytes as a little-endian integer.
+func leUint(b []byte) uint64 {
+	var buf [8]byte
+	copy(buf[:], b)
+	return binary.LittleEndian.Uint64(buf[:])
+}
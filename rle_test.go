@@ -0,0 +1,182 @@
+package qcodec
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRLE(t *testing.T) {
+
+	ta := require.New(t)
+
+	u8, err := RLE(U8{})
+	ta.NoError(err)
+	u32, err := RLE(U32{})
+	ta.NoError(err)
+	xy, _ := NewTypeCodec(typeXY{})
+	rxy, err := RLE(xy)
+	ta.NoError(err)
+
+	flags := make([]uint8, 10000)
+	for i := 5000; i < 5100; i++ {
+		flags[i] = 1
+	}
+
+	cases := []struct {
+		c     *RLECodec
+		input interface{}
+		runs  int
+	}{
+		{u8, []uint8{}, 0},
+		{u8, []uint8{1}, 1},
+		{u8, []uint8{1, 1, 2, 1}, 3},
+		{u8, flags, 3},
+		{u32, []uint32{7, 7, 7, 7, 8, 8, 9}, 3},
+		{rxy, []typeXY{{1, 2}, {1, 2}, {3, 4}}, 2},
+	}
+
+	for i, tc := range cases {
+		c := tc.c
+		b := c.Encode(tc.input)
+		ta.Equal(len(b), c.Size(tc.input), "%d-th: case: %+v", i+1, tc)
+		ta.Equal(len(b), c.EncodedSize(b))
+		ta.Equal(len(b), c.NeededSize(b))
+		ta.Equal(tc.runs, c.Runs(b))
+
+		n, v := c.Decode(append(b, 1))
+		ta.Equal(len(b), n)
+		ta.Equal(tc.input, v)
+
+		checkGet(ta, tc.input, c.Len(b), func(i int) interface{} { return c.Get(b, i) })
+		testPanic(t, func() { c.Get(b, c.Len(b)) }, "out of range")
+	}
+
+	// 3 runs of 1-byte values
+	ta.Equal(4+3*5, len(u8.Encode(flags)))
+
+	_, err = RLE(String16{})
+	ta.Equal(ErrNotFixedSize, errors.Cause(err))
+
+	// Dummy decodes to []interface{}
+	d, err := RLE(Dummy{})
+	ta.NoError(err)
+	_, v := d.Decode(d.Encode([]interface{}{nil, nil}))
+	ta.Equal([]interface{}{nil, nil}, v)
+	_, v = d.Decode(d.Encode([]interface{}{}))
+	ta.Equal([]interface{}{}, v)
+
+	testPanic(t, func() { u8.Encode(uint8(1)) }, "not slice")
+}
+
+func TestRLEWrappedElt(t *testing.T) {
+
+	ta := require.New(t)
+
+	sum, err := Checksummed(U32{}, CRC32C)
+	ta.NoError(err)
+	rs, err := ReedSolomon(U16{}, 2, 1)
+	ta.NoError(err)
+
+	input32 := []uint32{7, 7, 7, 8}
+	input16 := []uint16{3, 3, 1}
+
+	c, err := RLE(sum)
+	ta.NoError(err)
+	_, v := c.Decode(c.Encode(input32))
+	ta.Equal(input32, v)
+	ta.Equal(uint32(8), c.Get(c.Encode(input32), 3))
+
+	c, err = RLE(rs)
+	ta.NoError(err)
+	_, v = c.Decode(c.Encode(input16))
+	ta.Equal(input16, v)
+
+	h, err := HybridRLE(sum)
+	ta.NoError(err)
+	b := h.Encode(input32)
+	_, v = h.Decode(b)
+	ta.Equal(input32, v)
+	ta.Equal(uint32(7), h.Get(b, 1))
+}
+
+func TestHybridRLE(t *testing.T) {
+
+	ta := require.New(t)
+
+	rnd := rand.New(rand.NewSource(1))
+
+	u16, err := HybridRLE(U16{})
+	ta.NoError(err)
+	i64, err := HybridRLE(I64{})
+	ta.NoError(err)
+
+	mixed := make([]uint16, 0, 3000)
+	for len(mixed) < 3000 {
+		if rnd.Intn(2) == 0 {
+			v := uint16(rnd.Intn(5))
+			for k := rnd.Intn(50); k >= 0; k-- {
+				mixed = append(mixed, v)
+			}
+		} else {
+			for k := rnd.Intn(300); k >= 0; k-- {
+				mixed = append(mixed, uint16(rnd.Intn(16)))
+			}
+		}
+	}
+
+	cases := []struct {
+		c     *HybridRLECodec
+		input interface{}
+	}{
+		{u16, []uint16{}},
+		{u16, []uint16{3}},
+		{u16, []uint16{0, 0, 0, 0, 0, 0, 0, 0}},
+		{u16, []uint16{1, 2, 3, 3, 3, 3, 3, 3, 3, 3, 3, 65535}},
+		{u16, mixed},
+		{i64, []int64{-1, -1, 0, 1, 1 << 62, -1 << 63}},
+	}
+
+	for i, tc := range cases {
+		c := tc.c
+		b := c.Encode(tc.input)
+		ta.Equal(len(b), c.Size(tc.input), "%d-th", i+1)
+		ta.Equal(len(b), c.EncodedSize(b))
+		ta.Equal(len(b), c.NeededSize(b))
+
+		n, v := c.Decode(append(b, 1))
+		ta.Equal(len(b), n)
+		ta.Equal(tc.input, v, "%d-th", i+1)
+
+		checkGet(ta, tc.input, c.Len(b), func(i int) interface{} { return c.Get(b, i) })
+		testPanic(t, func() { c.Get(b, -1) }, "out of range")
+	}
+
+	// literal values below 16 take 4 bits: smaller than plain RLE
+	r16, _ := RLE(U16{})
+	ta.True(len(u16.Encode(mixed)) < len(r16.Encode(mixed)))
+	ta.True(len(u16.Encode(mixed)) < 2*len(mixed)/2)
+
+	// 1 run group and 1 literal group of 3 values in 2 bits
+	b := u16.Encode([]uint16{9, 9, 9, 9, 9, 9, 9, 9, 1, 2, 3})
+	ta.Equal(4+2*8+4+(1+2)+(1+1), len(b))
+	ta.Equal(4+2*8+4, u16.NeededSize(b[:4]))
+	ta.Equal(4, u16.NeededSize(nil))
+
+	_, err = HybridRLE(String16{})
+	ta.Equal(ErrNotFixedSize, errors.Cause(err))
+	xy, _ := NewTypeCodec(struct{ A, B, C int32 }{})
+	_, err = HybridRLE(xy)
+	ta.Equal(ErrNotFixedSize, errors.Cause(err))
+}
+
+func checkGet(ta *require.Assertions, input interface{}, n int, get func(i int) interface{}) {
+	sl := reflect.ValueOf(input)
+	ta.Equal(sl.Len(), n)
+	for i := 0; i < n; i++ {
+		ta.Equal(sl.Index(i).Interface(), get(i), "i: %d", i)
+	}
+}
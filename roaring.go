@@ -0,0 +1,433 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// container types of Roaring.
+const (
+	roaringArray  byte = 1
+	roaringBitmap byte = 2
+	roaringRun    byte = 3
+)
+
+// roaringDescSize is the size of a container descriptor: key, type,
+// cardinality-1 and offset.
+const roaringDescSize = 9
+
+// roaringBitmapWords is the number of uint64 in a bitmap container.
+const roaringBitmapWords = 1024
+
+// Roaring is a Codec of sets of uint32 in the Roaring bitmap format, compact
+// for both sparse and dense sets.
+// It accepts a []uint32 in any order and decodes to a sorted []uint32
+// without duplicates.
+// Contains, Union and Intersect work on the encoded bytes, containers not
+// involved are not decoded.
+//
+// Values are grouped into containers by the high 16 bits, the key.
+// A container stores the low 16 bits in one of 3 forms, whichever is the
+// smallest:
+//
+//     array   sorted uint16 * cardinality
+//     bitmap  uint64 * 1024, bit x is the (x%64)-th least significant bit of
+//             word x/64
+//     run     uint16 number of runs, then every run: uint16 start and uint16
+//             length-1, sorted by start
+//
+// The encoded layout is stable, integers are little-endian:
+//
+//     n         uint32 // number of containers
+//     containers: n times, sorted by key
+//       key     uint16
+//       type    uint8  // 1: array, 2: bitmap, 3: run
+//       card    uint16 // cardinality - 1
+//       offset  uint32 // offset of the container in data
+//     size      uint32 // size of data
+//     data      bytes
+type Roaring struct{}
+
+// Encode converts a []uint32 to bytes.
+func (c Roaring) Encode(d interface{}) []byte {
+	vals := append([]uint32{}, d.([]uint32)...)
+	sort.Slice(vals, func(i, j int) bool { return vals[i] < vals[j] })
+
+	var cts []roaringContainer
+	for i := 0; i < len(vals); {
+		key := uint16(vals[i] >> 16)
+
+		var lows []uint16
+		for ; i < len(vals) && uint16(vals[i]>>16) == key; i++ {
+			low := uint16(vals[i])
+			if len(lows) == 0 || lows[len(lows)-1] != low {
+				lows = append(lows, low)
+			}
+		}
+		cts = append(cts, newRoaringContainer(key, lows))
+	}
+	return marshalRoaring(cts)
+}
+
+// Decode converts bytes to a sorted []uint32.
+// It returns number bytes consumed and the slice.
+func (c Roaring) Decode(b []byte) (int, interface{}) {
+	r := readRoaring(b)
+
+	vals := make([]uint32, 0, r.cardinality())
+	for i := 0; i < r.n; i++ {
+		vals = r.container(i).appendTo(vals)
+	}
+	return r.size, vals
+}
+
+// Size returns the size in byte after encoding v.
+func (c Roaring) Size(d interface{}) int {
+	return len(c.Encode(d))
+}
+
+// EncodedSize returns size of the encoded set.
+func (c Roaring) EncodedSize(b []byte) int {
+	return readRoaring(b).size
+}
+
+// NeededSize returns the encoded size if the header and descriptors are
+// complete.
+func (c Roaring) NeededSize(b []byte) int {
+	if len(b) < 4 {
+		return 4
+	}
+	n := int(binary.LittleEndian.Uint32(b))
+	descEnd := 4 + n*roaringDescSize + 4
+	if len(b) < descEnd {
+		return descEnd
+	}
+	return c.EncodedSize(b)
+}
+
+// Len returns the number of elements in the set.
+func (c Roaring) Len(b []byte) int {
+	return readRoaring(b).cardinality()
+}
+
+// Contains returns true if x is in the set.
+func (c Roaring) Contains(b []byte, x uint32) bool {
+	r := readRoaring(b)
+	i, ok := r.find(uint16(x >> 16))
+	return ok && r.container(i).contains(uint16(x))
+}
+
+// Union returns the encoded union of two encoded sets.
+// Containers with a key only in one of them are copied as is.
+func (c Roaring) Union(a, b []byte) []byte {
+	x, y := readRoaring(a), readRoaring(b)
+
+	var cts []roaringContainer
+	i, j := 0, 0
+	for i < x.n || j < y.n {
+		switch {
+		case j == y.n || (i < x.n && x.key(i) < y.key(j)):
+			cts = append(cts, x.container(i))
+			i++
+		case i == x.n || y.key(j) < x.key(i):
+			cts = append(cts, y.container(j))
+			j++
+		default:
+			var words [roaringBitmapWords]uint64
+			x.container(i).orTo(&words)
+			y.container(j).orTo(&words)
+			cts = append(cts, roaringFromWords(x.key(i), &words))
+			i++
+			j++
+		}
+	}
+	return marshalRoaring(cts)
+}
+
+// Intersect returns the encoded intersection of two encoded sets.
+// Only containers with a key in both of them are decoded.
+func (c Roaring) Intersect(a, b []byte) []byte {
+	x, y := readRoaring(a), readRoaring(b)
+
+	var cts []roaringContainer
+	for i := 0; i < x.n; i++ {
+		j, ok := y.find(x.key(i))
+		if !ok {
+			continue
+		}
+
+		cx, cy := x.container(i), y.container(j)
+		if cy.typ == roaringArray {
+			cx, cy = cy, cx
+		}
+
+		var ct roaringContainer
+		var empty bool
+		if cx.typ == roaringArray {
+			var lows []uint16
+			for k := 0; k < cx.card; k++ {
+				low := binary.LittleEndian.Uint16(cx.data[k*2:])
+				if cy.contains(low) {
+					lows = append(lows, low)
+				}
+			}
+			empty = len(lows) == 0
+			if !empty {
+				ct = newRoaringContainer(cx.key, lows)
+			}
+		} else {
+			var wx, wy [roaringBitmapWords]uint64
+			cx.orTo(&wx)
+			cy.orTo(&wy)
+			for k := range wx {
+				wx[k] &= wy[k]
+			}
+			ct = roaringFromWords(cx.key, &wx)
+			empty = ct.card == 0
+		}
+
+		if !empty {
+			cts = append(cts, ct)
+		}
+	}
+	return marshalRoaring(cts)
+}
+
+// roaringContainer is one container, data is in the encoded form of typ.
+type roaringContainer struct {
+	key  uint16
+	typ  byte
+	card int
+	data []byte
+}
+
+// newRoaringContainer creates a container of the smallest form from sorted
+// unique lows.
+func newRoaringContainer(key uint16, lows []uint16) roaringContainer {
+
+	nruns := 0
+	for i, low := range lows {
+		if i == 0 || lows[i-1]+1 != low {
+			nruns++
+		}
+	}
+
+	arraySize := 2 * len(lows)
+	runSize := 2 + 4*nruns
+	bitmapSize := 8 * roaringBitmapWords
+
+	ct := roaringContainer{key: key, card: len(lows)}
+
+	switch {
+	case arraySize <= runSize && arraySize <= bitmapSize:
+		ct.typ = roaringArray
+		ct.data = make([]byte, 0, arraySize)
+		for _, low := range lows {
+			ct.data = appendU16(ct.data, low)
+		}
+	case runSize <= bitmapSize:
+		ct.typ = roaringRun
+		ct.data = make([]byte, 0, runSize)
+		ct.data = appendU16(ct.data, uint16(nruns))
+		for i := 0; i < len(lows); {
+			j := i + 1
+			for j < len(lows) && lows[j-1]+1 == lows[j] {
+				j++
+			}
+			ct.data = appendU16(ct.data, lows[i])
+			ct.data = appendU16(ct.data, uint16(j-i-1))
+			i = j
+		}
+	default:
+		var words [roaringBitmapWords]uint64
+		for _, low := range lows {
+			words[low/64] |= 1 << (low % 64)
+		}
+		ct.typ = roaringBitmap
+		ct.data = make([]byte, 0, bitmapSize)
+		for _, w := range words {
+			ct.data = appendU64(ct.data, w)
+		}
+	}
+	return ct
+}
+
+func roaringFromWords(key uint16, words *[roaringBitmapWords]uint64) roaringContainer {
+	var lows []uint16
+	for i, w := range words {
+		for w != 0 {
+			lows = append(lows, uint16(i*64+bits.TrailingZeros64(w)))
+			w &= w - 1
+		}
+	}
+	return newRoaringContainer(key, lows)
+}
+
+func (ct roaringContainer) size() int {
+	switch ct.typ {
+	case roaringArray:
+		return 2 * ct.card
+	case roaringBitmap:
+		return 8 * roaringBitmapWords
+	case roaringRun:
+		return 2 + 4*int(binary.LittleEndian.Uint16(ct.data))
+	}
+	panic(errors.Wrapf(ErrMalformed, "roaring container type: %d", ct.typ))
+}
+
+func (ct roaringContainer) run(i int) (uint16, int) {
+	p := ct.data[2+i*4:]
+	return binary.LittleEndian.Uint16(p), int(binary.LittleEndian.Uint16(p[2:])) + 1
+}
+
+func (ct roaringContainer) contains(low uint16) bool {
+	switch ct.typ {
+	case roaringArray:
+		i := sort.Search(ct.card, func(i int) bool {
+			return binary.LittleEndian.Uint16(ct.data[i*2:]) >= low
+		})
+		return i < ct.card && binary.LittleEndian.Uint16(ct.data[i*2:]) == low
+	case roaringBitmap:
+		return binary.LittleEndian.Uint64(ct.data[low/64*8:])&(1<<(low%64)) != 0
+	case roaringRun:
+		nruns := int(binary.LittleEndian.Uint16(ct.data))
+		// the first run starting after low
+		i := sort.Search(nruns, func(i int) bool {
+			start, _ := ct.run(i)
+			return start > low
+		})
+		if i == 0 {
+			return false
+		}
+		start, length := ct.run(i - 1)
+		return int(low) < int(start)+length
+	}
+	panic(errors.Wrapf(ErrMalformed, "roaring container type: %d", ct.typ))
+}
+
+// appendTo appends all values in the container to vals.
+func (ct roaringContainer) appendTo(vals []uint32) []uint32 {
+	high := uint32(ct.key) << 16
+
+	switch ct.typ {
+	case roaringArray:
+		for i := 0; i < ct.card; i++ {
+			vals = append(vals, high|uint32(binary.LittleEndian.Uint16(ct.data[i*2:])))
+		}
+	case roaringBitmap:
+		for i := 0; i < roaringBitmapWords; i++ {
+			w := binary.LittleEndian.Uint64(ct.data[i*8:])
+			for w != 0 {
+				vals = append(vals, high|uint32(i*64+bits.TrailingZeros64(w)))
+				w &= w - 1
+			}
+		}
+	case roaringRun:
+		nruns := int(binary.LittleEndian.Uint16(ct.data))
+		for i := 0; i < nruns; i++ {
+			start, length := ct.run(i)
+			for k := 0; k < length; k++ {
+				vals = append(vals, high|(uint32(start)+uint32(k)))
+			}
+		}
+	default:
+		panic(errors.Wrapf(ErrMalformed, "roaring container type: %d", ct.typ))
+	}
+	return vals
+}
+
+// orTo sets the bits of all values in the container in words.
+func (ct roaringContainer) orTo(words *[roaringBitmapWords]uint64) {
+	if ct.typ == roaringBitmap {
+		for i := range words {
+			words[i] |= binary.LittleEndian.Uint64(ct.data[i*8:])
+		}
+		return
+	}
+
+	for _, v := range ct.appendTo(nil) {
+		low := uint16(v)
+		words[low/64] |= 1 << (low % 64)
+	}
+}
+
+func marshalRoaring(cts []roaringContainer) []byte {
+	dataSize := 0
+	for _, ct := range cts {
+		dataSize += len(ct.data)
+	}
+
+	rst := make([]byte, 0, 4+len(cts)*roaringDescSize+4+dataSize)
+	rst = appendU32(rst, len(cts))
+
+	off := 0
+	for _, ct := range cts {
+		rst = appendU16(rst, ct.key)
+		rst = append(rst, ct.typ)
+		rst = appendU16(rst, uint16(ct.card-1))
+		rst = appendU32(rst, off)
+		off += len(ct.data)
+	}
+
+	rst = appendU32(rst, dataSize)
+	for _, ct := range cts {
+		rst = append(rst, ct.data...)
+	}
+	return rst
+}
+
+// roaring is a view of an encoded Roaring set.
+type roaring struct {
+	n    int
+	desc []byte
+	data []byte
+	size int
+}
+
+func readRoaring(b []byte) *roaring {
+	n := int(binary.LittleEndian.Uint32(b))
+	descEnd := 4 + n*roaringDescSize
+	dataStart := descEnd + 4
+	size := dataStart + int(binary.LittleEndian.Uint32(b[descEnd:]))
+
+	return &roaring{
+		n:    n,
+		desc: b[4:descEnd],
+		data: b[dataStart:size],
+		size: size,
+	}
+}
+
+func (r *roaring) key(i int) uint16 {
+	return binary.LittleEndian.Uint16(r.desc[i*roaringDescSize:])
+}
+
+func (r *roaring) container(i int) roaringContainer {
+	d := r.desc[i*roaringDescSize:]
+	ct := roaringContainer{
+		key:  binary.LittleEndian.Uint16(d),
+		typ:  d[2],
+		card: int(binary.LittleEndian.Uint16(d[3:])) + 1,
+	}
+	off := int(binary.LittleEndian.Uint32(d[5:]))
+	ct.data = r.data[off:]
+	ct.data = ct.data[:ct.size()]
+	return ct
+}
+
+func (r *roaring) cardinality() int {
+	n := 0
+	for i := 0; i < r.n; i++ {
+		n += int(binary.LittleEndian.Uint16(r.desc[i*roaringDescSize+3:])) + 1
+	}
+	return n
+}
+
+// find returns the index of the container with key.
+func (r *roaring) find(key uint16) (int, bool) {
+	i := sort.Search(r.n, func(i int) bool { return r.key(i) >= key })
+	return i, i < r.n && r.key(i) == key
+}
@@ -0,0 +1,168 @@
+package qcodec
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoaring(t *testing.T) {
+
+	ta := require.New(t)
+
+	rnd := rand.New(rand.NewSource(1))
+
+	sparse := make([]uint32, 1000)
+	for i := range sparse {
+		sparse[i] = rnd.Uint32()
+	}
+
+	dense := make([]uint32, 0, 30000)
+	for i := 0; i < 60000; i++ {
+		if rnd.Intn(2) == 0 {
+			dense = append(dense, 1<<16+uint32(i))
+		}
+	}
+
+	runs := make([]uint32, 0, 65536)
+	for i := uint32(0); i < 65536; i++ {
+		runs = append(runs, 5<<16+i)
+	}
+
+	cases := [][]uint32{
+		{},
+		{0},
+		{0xffffffff},
+		{3, 1, 2, 2, 1 << 20, 1<<20 + 1},
+		sparse,
+		dense,
+		runs,
+		append(append(append([]uint32{}, sparse...), dense...), runs...),
+	}
+
+	c := Roaring{}
+
+	for i, input := range cases {
+		want := sortedUniqueU32(input)
+
+		b := c.Encode(input)
+		ta.Equal(len(b), c.Size(input), "%d-th", i+1)
+		ta.Equal(len(b), c.EncodedSize(b))
+		ta.Equal(len(b), c.NeededSize(b))
+
+		n, v := c.Decode(append(b, 1))
+		ta.Equal(len(b), n)
+		ta.Equal(want, v, "%d-th", i+1)
+		ta.Equal(len(want), c.Len(b))
+
+		for _, x := range want {
+			ta.True(c.Contains(b, x))
+			ta.Equal(containsU32(want, x+1), c.Contains(b, x+1))
+		}
+	}
+
+	// forms
+	ta.Equal(4+roaringDescSize+4+2*3, len(c.Encode([]uint32{1, 5, 9})))
+	ta.Equal(4+roaringDescSize+4+2+4, len(c.Encode(runs)))
+	ta.Equal(4+roaringDescSize+4+8192, len(c.Encode(dense)))
+
+	ta.Equal(4, c.NeededSize(nil))
+	b := c.Encode(sparse)
+	ta.Equal(4+readRoaring(b).n*roaringDescSize+4, c.NeededSize(b[:4]))
+}
+
+func TestRoaringContains(t *testing.T) {
+
+	ta := require.New(t)
+
+	c := Roaring{}
+
+	b := c.Encode([]uint32{10, 11, 12, 20, 1 << 16, 3<<16 + 7})
+	for _, x := range []uint32{9, 13, 19, 21, 1<<16 + 1, 2 << 16, 3<<16 + 6, 3<<16 + 8} {
+		ta.False(c.Contains(b, x), "x: %d", x)
+	}
+
+	b = c.Encode([]uint32{})
+	ta.False(c.Contains(b, 0))
+}
+
+func TestRoaringSetOps(t *testing.T) {
+
+	ta := require.New(t)
+
+	rnd := rand.New(rand.NewSource(2))
+
+	gen := func(n int, max uint32) []uint32 {
+		rst := make([]uint32, n)
+		for i := range rst {
+			rst[i] = uint32(rnd.Int63n(int64(max)))
+		}
+		return rst
+	}
+
+	runs := func(from, to uint32) []uint32 {
+		var rst []uint32
+		for i := from; i < to; i++ {
+			rst = append(rst, i)
+		}
+		return rst
+	}
+
+	sets := [][]uint32{
+		{},
+		gen(10, 1<<20),
+		gen(5000, 1<<17),
+		gen(50000, 1<<18),
+		runs(1000, 70000),
+		runs(60000, 200000),
+	}
+
+	c := Roaring{}
+
+	for _, x := range sets {
+		for _, y := range sets {
+			bx, by := c.Encode(x), c.Encode(y)
+
+			u := c.Union(bx, by)
+			_, got := c.Decode(u)
+			ta.Equal(sortedUniqueU32(append(append([]uint32{}, x...), y...)), got)
+			ta.Equal(c.Encode(append(append([]uint32{}, x...), y...)), u)
+
+			in := c.Intersect(bx, by)
+			_, got = c.Decode(in)
+			ta.Equal(intersectU32(x, y), got)
+			ta.Equal(c.Encode(intersectU32(x, y)), in)
+		}
+	}
+}
+
+func sortedUniqueU32(vals []uint32) []uint32 {
+	vals = append([]uint32{}, vals...)
+	sort.Slice(vals, func(i, j int) bool { return vals[i] < vals[j] })
+
+	rst := []uint32{}
+	for i, v := range vals {
+		if i == 0 || vals[i-1] != v {
+			rst = append(rst, v)
+		}
+	}
+	return rst
+}
+
+func containsU32(sorted []uint32, x uint32) bool {
+	i := sort.Search(len(sorted), func(i int) bool { return sorted[i] >= x })
+	return i < len(sorted) && sorted[i] == x
+}
+
+func intersectU32(x, y []uint32) []uint32 {
+	sy := sortedUniqueU32(y)
+	rst := []uint32{}
+	for _, v := range sortedUniqueU32(x) {
+		if containsU32(sy, v) {
+			rst = append(rst, v)
+		}
+	}
+	return rst
+}
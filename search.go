@@ -0,0 +1,206 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Search returns the index of the first element in buf that is not less than
+// key, i.e., the lower bound of key.
+// It returns the number of elements in buf if all elements are less than key.
+//
+// buf is a sorted concatenation of elements encoded with c, and c must be a
+// fixed size Codec.
+//
+// Elements are compared with CompareEncoded: if c is order-preserving, i.e.,
+// the order of encoded bytes is the same as the order of values, such as a
+// big-endian TypeCodec of unsigned integers, elements are compared in encoded
+// form without decoding.
+func Search(buf []byte, c Codec, key interface{}) (int, error) {
+
+	size, err := searchEltSize(buf, c)
+	if err != nil {
+		return 0, err
+	}
+	if size == 0 {
+		return 0, nil
+	}
+
+	cmp := keyComparator(c, key)
+
+	n := len(buf) / size
+	i := sort.Search(n, func(i int) bool {
+		return cmp(buf[i*size:(i+1)*size]) >= 0
+	})
+	return i, nil
+}
+
+// SearchExact returns the index of the first element in buf that equals key
+// and true.
+// If there is no such element it returns the index where key would be inserted
+// and false.
+//
+// See Search for requirements on buf and c.
+func SearchExact(buf []byte, c Codec, key interface{}) (int, bool, error) {
+
+	i, err := Search(buf, c, key)
+	if err != nil {
+		return 0, false, err
+	}
+
+	size, _ := fixedSize(c)
+	if size == 0 || (i+1)*size > len(buf) {
+		return i, false, nil
+	}
+
+	cmp := keyComparator(c, key)
+	return i, cmp(buf[i*size:(i+1)*size]) == 0, nil
+}
+
+// searchEltSize returns the element size in buf.
+func searchEltSize(buf []byte, c Codec) (int, error) {
+
+	size, fixed := fixedSize(c)
+	if !fixed {
+		return 0, errors.Wrapf(ErrNotFixedSize, "codec: %#v", c)
+	}
+
+	if size > 0 && len(buf)%size != 0 {
+		return 0, errors.Wrapf(ErrMalformed, "buffer size: %d, element size: %d", len(buf), size)
+	}
+	return size, nil
+}
+
+// keyComparator returns a function that compares an encoded element with key.
+func keyComparator(c Codec, key interface{}) func(elt []byte) int {
+	k := c.Encode(key)
+	return func(elt []byte) int {
+		return CompareEncoded(c, elt, k)
+	}
+}
+
+// orderPreserving returns true if the order of encoded bytes of c is the same
+// as the order of values.
+func orderPreserving(c Codec) bool {
+	switch t := c.(type) {
+	case U8, Bytes, Dummy:
+		return true
+	case *TypeCodec:
+		return t.byteOrder == binary.BigEndian && unsignedType(t.typ)
+	}
+	return false
+}
+
+// unsignedType returns true if t is composed of only unsigned integers and
+// bool, which are order-preserving in big-endian.
+func unsignedType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Array:
+		return unsignedType(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if !unsignedType(t.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compareValues compares two values of the same type.
+// Integers, floats, bool, string and array, slice or struct of them are
+// supported.
+// Arrays, slices and structs are compared element by element, field by field.
+//
+// It returns -1, 0 or 1 if a is less than, equal to or greater than b.
+// It panics if the type is not supported.
+func compareValues(a, b interface{}) int {
+	return compareReflect(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+func compareReflect(a, b reflect.Value) int {
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmpInt64(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmpUint64(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		x, y := a.Float(), b.Float()
+		if x < y {
+			return -1
+		}
+		if x > y {
+			return 1
+		}
+		return 0
+	case reflect.Bool:
+		return cmpBool(a.Bool(), b.Bool())
+	case reflect.String:
+		return cmpString(a.String(), b.String())
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < a.Len() && i < b.Len(); i++ {
+			if r := compareReflect(a.Index(i), b.Index(i)); r != 0 {
+				return r
+			}
+		}
+		return cmpInt64(int64(a.Len()), int64(b.Len()))
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if r := compareReflect(a.Field(i), b.Field(i)); r != 0 {
+				return r
+			}
+		}
+		return 0
+	case reflect.Ptr, reflect.Interface:
+		return compareReflect(a.Elem(), b.Elem())
+	}
+
+	panic(errors.Errorf("can not compare type: %v", a.Type()))
+}
+
+func cmpInt64(a, b int64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+func cmpUint64(a, b uint64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+func cmpBool(a, b bool) int {
+	if a == b {
+		return 0
+	}
+	if b {
+		return -1
+	}
+	return 1
+}
+
+func cmpString(a, b string) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,132 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type typeKV struct {
+	K uint32
+	V uint16
+}
+
+func encodeAll(c Codec, vals ...interface{}) []byte {
+	var b []byte
+	for _, v := range vals {
+		b = append(b, c.Encode(v)...)
+	}
+	return b
+}
+
+func TestSearch(t *testing.T) {
+
+	ta := require.New(t)
+
+	u64be, err := NewTypeCodec(uint64(0), binary.BigEndian)
+	ta.NoError(err)
+
+	kv, err := NewTypeCodec(typeKV{}, binary.BigEndian)
+	ta.NoError(err)
+
+	xy, err := NewTypeCodec(typeXY{})
+	ta.NoError(err)
+
+	cases := []struct {
+		codec     Codec
+		buf       []byte
+		key       interface{}
+		want      int
+		wantFound bool
+	}{
+		{U32{}, encodeAll(U32{}), uint32(1), 0, false},
+		{U32{}, encodeAll(U32{}, uint32(1), uint32(3), uint32(0x100)), uint32(0), 0, false},
+		{U32{}, encodeAll(U32{}, uint32(1), uint32(3), uint32(0x100)), uint32(3), 1, true},
+		{U32{}, encodeAll(U32{}, uint32(1), uint32(3), uint32(0x100)), uint32(4), 2, false},
+		{U32{}, encodeAll(U32{}, uint32(1), uint32(3), uint32(0x100)), uint32(0x100), 2, true},
+		{U32{}, encodeAll(U32{}, uint32(1), uint32(3), uint32(0x100)), uint32(0x101), 3, false},
+		{I16{}, encodeAll(I16{}, int16(-5), int16(-1), int16(2)), int16(-1), 1, true},
+		{I16{}, encodeAll(I16{}, int16(-5), int16(-1), int16(2)), int16(0), 2, false},
+		{U8{}, encodeAll(U8{}, uint8(1), uint8(1), uint8(9)), uint8(1), 0, true},
+		{u64be, encodeAll(u64be, uint64(1), uint64(0x100), uint64(0x10000)), uint64(0x100), 1, true},
+		{u64be, encodeAll(u64be, uint64(1), uint64(0x100), uint64(0x10000)), uint64(0xff), 1, false},
+		{kv, encodeAll(kv, typeKV{1, 2}, typeKV{1, 5}, typeKV{2, 0}), typeKV{1, 5}, 1, true},
+		{kv, encodeAll(kv, typeKV{1, 2}, typeKV{1, 5}, typeKV{2, 0}), &typeKV{1, 6}, 2, false},
+		{xy, encodeAll(xy, typeXY{-1, 2}, typeXY{0, -5}, typeXY{0, 3}), typeXY{0, 0}, 2, false},
+		{xy, encodeAll(xy, typeXY{-1, 2}, typeXY{0, -5}, typeXY{0, 3}), typeXY{0, -5}, 1, true},
+		{Bytes{size: 2}, []byte("aabbcc"), []byte("bb"), 1, true},
+		{Dummy{}, nil, 1, 0, false},
+	}
+
+	for i, c := range cases {
+		idx, err := Search(c.buf, c.codec, c.key)
+		ta.NoError(err)
+		ta.Equal(c.want, idx, "%d-th: case: %+v", i+1, c)
+
+		idx, found, err := SearchExact(c.buf, c.codec, c.key)
+		ta.NoError(err)
+		ta.Equal(c.want, idx, "%d-th: case: %+v", i+1, c)
+		ta.Equal(c.wantFound, found, "%d-th: case: %+v", i+1, c)
+	}
+}
+
+func TestSearchError(t *testing.T) {
+
+	ta := require.New(t)
+
+	_, err := Search([]byte{0, 1}, String16{}, "a")
+	ta.Equal(ErrNotFixedSize, errors.Cause(err))
+
+	_, _, err = SearchExact([]byte{0, 1, 2}, U16{}, uint16(1))
+	ta.Equal(ErrMalformed, errors.Cause(err))
+}
+
+func TestOrderPreserving(t *testing.T) {
+
+	ta := require.New(t)
+
+	mk := func(v interface{}, endian binary.ByteOrder) Codec {
+		c, err := NewTypeCodec(v, endian)
+		ta.NoError(err)
+		return c
+	}
+
+	ta.True(orderPreserving(U8{}))
+	ta.True(orderPreserving(Bytes{size: 3}))
+	ta.False(orderPreserving(U16{}))
+	ta.False(orderPreserving(I8{}))
+	ta.True(orderPreserving(mk(uint32(0), binary.BigEndian)))
+	ta.True(orderPreserving(mk(typeKV{}, binary.BigEndian)))
+	ta.True(orderPreserving(mk([2]uint16{}, binary.BigEndian)))
+	ta.False(orderPreserving(mk(uint32(0), binary.LittleEndian)))
+	ta.False(orderPreserving(mk(int32(0), binary.BigEndian)))
+	ta.False(orderPreserving(mk(typeXY{}, binary.BigEndian)))
+}
+
+func TestCompareValues(t *testing.T) {
+
+	ta := require.New(t)
+
+	cases := []struct {
+		a, b interface{}
+		want int
+	}{
+		{int8(-1), int8(1), -1},
+		{uint64(3), uint64(3), 0},
+		{float64(2), float64(1), 1},
+		{false, true, -1},
+		{"ab", "a", 1},
+		{[]byte("ab"), []byte("abc"), -1},
+		{[2]int32{1, 2}, [2]int32{1, 3}, -1},
+		{typeXY{1, 2}, typeXY{1, 1}, 1},
+	}
+
+	for i, c := range cases {
+		ta.Equal(c.want, compareValues(c.a, c.b), "%d-th: case: %+v", i+1, c)
+		ta.Equal(-c.want, compareValues(c.b, c.a), "%d-th: case: %+v", i+1, c)
+	}
+
+	testPanic(t, func() { compareValues(map[int]int{}, map[int]int{}) }, "map")
+}
@@ -0,0 +1,263 @@
+package qcodec
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// signedKeyIDSize is the size of the key id stored with a signature.
+const signedKeyIDSize = 4
+
+// ErrUnknownKeyID indicates a key id is not found in a Signer.
+var ErrUnknownKeyID = errors.New("unknown key id")
+
+// A Signer signs messages with an active key and verifies signatures made by
+// any key it knows.
+// Keys are identified by ids, so that a key can be rotated while values
+// signed by old keys are still verifiable.
+type Signer interface {
+	// KeyID returns the id of the active key to sign with.
+	KeyID() uint32
+	// SignatureSize returns the size of a signature.
+	SignatureSize() int
+	// Sign signs msg with the active key.
+	Sign(msg []byte) []byte
+	// Verify verifies sig of msg is signed by key "keyID".
+	Verify(keyID uint32, msg, sig []byte) error
+}
+
+// HMACSigner is a Signer with HMAC-SHA256.
+type HMACSigner struct {
+	active uint32
+	keys   map[uint32][]byte
+}
+
+// NewHMACSigner creates a *HMACSigner with secret keys by id.
+// activeID is the id of the key to sign with and must be one of keys.
+func NewHMACSigner(activeID uint32, keys map[uint32][]byte) (*HMACSigner, error) {
+	if _, ok := keys[activeID]; !ok {
+		return nil, errors.Wrapf(ErrUnknownKeyID, "active key id: %d", activeID)
+	}
+
+	s := &HMACSigner{
+		active: activeID,
+		keys:   make(map[uint32][]byte, len(keys)),
+	}
+	for id, k := range keys {
+		s.keys[id] = append([]byte{}, k...)
+	}
+	return s, nil
+}
+
+// KeyID returns the id of the active key.
+func (s *HMACSigner) KeyID() uint32 {
+	return s.active
+}
+
+// SignatureSize returns 32.
+func (s *HMACSigner) SignatureSize() int {
+	return sha256.Size
+}
+
+// Sign returns HMAC-SHA256 of msg with the active key.
+func (s *HMACSigner) Sign(msg []byte) []byte {
+	return hmacSHA256(s.keys[s.active], msg)
+}
+
+// Verify checks sig is the HMAC-SHA256 of msg with key "keyID".
+func (s *HMACSigner) Verify(keyID uint32, msg, sig []byte) error {
+	k, ok := s.keys[keyID]
+	if !ok {
+		return errors.Wrapf(ErrUnknownKeyID, "key id: %d", keyID)
+	}
+	if !hmac.Equal(hmacSHA256(k, msg), sig) {
+		return errors.Wrapf(ErrAuthFailed, "hmac-sha256 key id: %d", keyID)
+	}
+	return nil
+}
+
+func hmacSHA256(key, msg []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write(msg)
+	return h.Sum(nil)
+}
+
+// Ed25519Signer is a Signer with ed25519.
+type Ed25519Signer struct {
+	active uint32
+	priv   ed25519.PrivateKey
+	pubs   map[uint32]ed25519.PublicKey
+}
+
+// NewEd25519Signer creates a *Ed25519Signer that signs with private key
+// "priv" as key "activeID", and verifies with public keys "pubs" by id.
+// The public key of "priv" is added to "pubs" as key "activeID".
+//
+// If priv is nil, it can only verify, and Sign panics.
+func NewEd25519Signer(activeID uint32, priv ed25519.PrivateKey, pubs map[uint32]ed25519.PublicKey) (*Ed25519Signer, error) {
+	s := &Ed25519Signer{
+		active: activeID,
+		pubs:   make(map[uint32]ed25519.PublicKey, len(pubs)+1),
+	}
+
+	for id, pub := range pubs {
+		if len(pub) != ed25519.PublicKeySize {
+			return nil, errors.Errorf("invalid ed25519 public key size: %d, key id: %d", len(pub), id)
+		}
+		s.pubs[id] = pub
+	}
+
+	if priv != nil {
+		if len(priv) != ed25519.PrivateKeySize {
+			return nil, errors.Errorf("invalid ed25519 private key size: %d", len(priv))
+		}
+		s.priv = priv
+		s.pubs[activeID] = priv.Public().(ed25519.PublicKey)
+	}
+
+	return s, nil
+}
+
+// KeyID returns the id of the private key.
+func (s *Ed25519Signer) KeyID() uint32 {
+	return s.active
+}
+
+// SignatureSize returns 64.
+func (s *Ed25519Signer) SignatureSize() int {
+	return ed25519.SignatureSize
+}
+
+// Sign signs msg with the private key.
+// It panics if there is no private key.
+func (s *Ed25519Signer) Sign(msg []byte) []byte {
+	if s.priv == nil {
+		panic(errors.Errorf("no ed25519 private key to sign"))
+	}
+	return ed25519.Sign(s.priv, msg)
+}
+
+// Verify checks sig is the ed25519 signature of msg by key "keyID".
+func (s *Ed25519Signer) Verify(keyID uint32, msg, sig []byte) error {
+	pub, ok := s.pubs[keyID]
+	if !ok {
+		return errors.Wrapf(ErrUnknownKeyID, "key id: %d", keyID)
+	}
+	if !ed25519.Verify(pub, msg, sig) {
+		return errors.Wrapf(ErrAuthFailed, "ed25519 key id: %d", keyID)
+	}
+	return nil
+}
+
+// SignedCodec appends a key id and a signature, or a MAC, to the encoding of
+// an inner Codec and verifies it when decoding.
+//
+// An encoded value is the inner encoding followed by the little-endian uint32
+// key id and the signature of both of them.
+// If the inner Codec is fixed size, so is SignedCodec.
+type SignedCodec struct {
+	inner   Codec
+	signer  Signer
+	sigSize int
+}
+
+// Signed creates a *SignedCodec that signs the encoding of inner with signer.
+func Signed(inner Codec, signer Signer) (*SignedCodec, error) {
+	if signer == nil {
+		return nil, errors.Errorf("signer is nil")
+	}
+
+	return &SignedCodec{
+		inner:   inner,
+		signer:  signer,
+		sigSize: signedKeyIDSize + signer.SignatureSize(),
+	}, nil
+}
+
+// Encode encodes d with the inner Codec and appends the active key id and the
+// signature.
+func (c *SignedCodec) Encode(d interface{}) []byte {
+	b := c.inner.Encode(d)
+
+	var id [signedKeyIDSize]byte
+	binary.LittleEndian.PutUint32(id[:], c.signer.KeyID())
+
+	b = append(b, id[:]...)
+	return append(b, c.signer.Sign(b)...)
+}
+
+// Decode verifies the signature and decodes a value.
+// It returns number bytes consumed and the value decoded by the inner Codec.
+// It panics with an error wrapping ErrAuthFailed if verification fails, or
+// ErrUnknownKeyID if the key is not known to the Signer.
+func (c *SignedCodec) Decode(b []byte) (int, interface{}) {
+	n, v, err := c.DecodeChecked(b)
+	if err != nil {
+		panic(err)
+	}
+	return n, v
+}
+
+// DecodeChecked is the same as Decode except it returns an error.
+func (c *SignedCodec) DecodeChecked(b []byte) (int, interface{}, error) {
+	n, err := c.NeededSizeChecked(b)
+	if err != nil {
+		return 0, nil, err
+	}
+	if n > len(b) {
+		return 0, nil, errors.Wrapf(ErrShortBuffer, "need: %d, got: %d", n, len(b))
+	}
+
+	msgEnd := n - c.signer.SignatureSize()
+	dataEnd := msgEnd - signedKeyIDSize
+
+	keyID := binary.LittleEndian.Uint32(b[dataEnd:msgEnd])
+	err = c.signer.Verify(keyID, b[:msgEnd], b[msgEnd:n])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	_, v, err := decodeChecked(c.inner, b[:dataEnd])
+	if err != nil {
+		return 0, nil, err
+	}
+	return n, v, nil
+}
+
+// Size returns the size of the inner encoding plus key id and signature.
+func (c *SignedCodec) Size(d interface{}) int {
+	return c.inner.Size(d) + c.sigSize
+}
+
+// EncodedSize returns the size of the inner encoding plus key id and
+// signature.
+func (c *SignedCodec) EncodedSize(b []byte) int {
+	return c.inner.EncodedSize(b) + c.sigSize
+}
+
+// NeededSize returns the size needed by the inner Codec plus key id and
+// signature.
+// It panics if the inner Codec finds a corrupted size header.
+func (c *SignedCodec) NeededSize(b []byte) int {
+	return mustNeededSize(c.NeededSizeChecked(b))
+}
+
+// NeededSizeChecked is the same as NeededSize except it returns the error of
+// the inner Codec.
+func (c *SignedCodec) NeededSizeChecked(b []byte) (int, error) {
+	n, err := neededSizeChecked(c.inner, b)
+	if err != nil {
+		return 0, err
+	}
+	return n + c.sigSize, nil
+}
+
+// Compare compares two values with the inner Codec.
+// Signatures are not verified.
+func (c *SignedCodec) Compare(a, b []byte) int {
+	return CompareEncoded(c.inner, a, b)
+}
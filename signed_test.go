@@ -0,0 +1,234 @@
+package qcodec
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEd25519(t *testing.T, seed byte) ed25519.PrivateKey {
+	s := make([]byte, ed25519.SeedSize)
+	s[0] = seed
+	return ed25519.NewKeyFromSeed(s)
+}
+
+func TestSigned(t *testing.T) {
+
+	ta := require.New(t)
+
+	hs, err := NewHMACSigner(1, map[uint32][]byte{1: []byte("k1"), 2: []byte("k2")})
+	ta.NoError(err)
+
+	es, err := NewEd25519Signer(7, newTestEd25519(t, 1), nil)
+	ta.NoError(err)
+
+	xy, err := NewTypeCodec(typeXY{})
+	ta.NoError(err)
+
+	cases := []struct {
+		inner   Codec
+		signer  Signer
+		input   interface{}
+		sigSize int
+	}{
+		{String16{}, hs, "", 4 + 32},
+		{String16{}, hs, "hello", 4 + 32},
+		{U32{}, hs, uint32(3), 4 + 32},
+		{String16{}, es, "hello", 4 + 64},
+		{xy, es, typeXY{1, 2}, 4 + 64},
+	}
+
+	for i, c := range cases {
+		m, err := Signed(c.inner, c.signer)
+		ta.NoError(err)
+
+		b := m.Encode(c.input)
+		want := c.inner.Size(c.input) + c.sigSize
+		ta.Equal(want, len(b), "%d-th: case: %+v", i+1, c)
+		ta.Equal(want, m.Size(c.input))
+		ta.Equal(want, m.EncodedSize(b))
+		ta.Equal(want, m.NeededSize(b))
+
+		n, v := m.Decode(append(b, 0))
+		ta.Equal(want, n)
+		ta.Equal(c.input, v)
+
+		ta.Equal(0, m.Compare(b, b))
+
+		for j := 0; j < len(b); j++ {
+			bad := append([]byte{}, b...)
+			bad[j] ^= 0x80
+
+			_, _, err := m.DecodeChecked(bad)
+			cause := errors.Cause(err)
+			ta.True(cause == ErrAuthFailed || cause == ErrUnknownKeyID || cause == ErrShortBuffer,
+				"%d-th: byte: %d: %v", i+1, j, err)
+		}
+	}
+
+	m, err := Signed(xy, es)
+	ta.NoError(err)
+	n, ok := fixedSize(m)
+	ta.True(ok)
+	ta.Equal(8+4+64, n)
+}
+
+func TestSignedKeyRotation(t *testing.T) {
+
+	ta := require.New(t)
+
+	keys := map[uint32][]byte{1: []byte("old"), 2: []byte("new")}
+
+	s1, err := NewHMACSigner(1, keys)
+	ta.NoError(err)
+	s2, err := NewHMACSigner(2, keys)
+	ta.NoError(err)
+
+	m1, err := Signed(String16{}, s1)
+	ta.NoError(err)
+	m2, err := Signed(String16{}, s2)
+	ta.NoError(err)
+
+	old := m1.Encode("x")
+	_, v := m2.Decode(old)
+	ta.Equal("x", v)
+
+	// old key removed
+	s3, err := NewHMACSigner(2, map[uint32][]byte{2: []byte("new")})
+	ta.NoError(err)
+	m3, err := Signed(String16{}, s3)
+	ta.NoError(err)
+
+	_, _, err = m3.DecodeChecked(old)
+	ta.Equal(ErrUnknownKeyID, errors.Cause(err))
+
+	_, v = m3.Decode(m2.Encode("y"))
+	ta.Equal("y", v)
+
+	// ed25519: sign with new key and verify old ones with public keys only
+	k1 := newTestEd25519(t, 1)
+	k2 := newTestEd25519(t, 2)
+
+	e1, err := NewEd25519Signer(1, k1, nil)
+	ta.NoError(err)
+	e2, err := NewEd25519Signer(2, k2, map[uint32]ed25519.PublicKey{1: k1.Public().(ed25519.PublicKey)})
+	ta.NoError(err)
+	verifier, err := NewEd25519Signer(0, nil, map[uint32]ed25519.PublicKey{
+		1: k1.Public().(ed25519.PublicKey),
+		2: k2.Public().(ed25519.PublicKey),
+	})
+	ta.NoError(err)
+
+	me1, _ := Signed(String16{}, e1)
+	me2, _ := Signed(String16{}, e2)
+	mv, _ := Signed(String16{}, verifier)
+
+	b1 := me1.Encode("a")
+	b2 := me2.Encode("b")
+
+	_, v = me2.Decode(b1)
+	ta.Equal("a", v)
+	_, v = mv.Decode(b1)
+	ta.Equal("a", v)
+	_, v = mv.Decode(b2)
+	ta.Equal("b", v)
+
+	_, _, err = me1.DecodeChecked(b2)
+	ta.Equal(ErrUnknownKeyID, errors.Cause(err))
+
+	testPanic(t, func() { mv.Encode("c") }, "verify only")
+
+	// a signature by a different key with the same id
+	e3, err := NewEd25519Signer(1, k2, nil)
+	ta.NoError(err)
+	me3, _ := Signed(String16{}, e3)
+	_, _, err = mv.DecodeChecked(me3.Encode("a"))
+	ta.Equal(ErrAuthFailed, errors.Cause(err))
+}
+
+func TestSignedError(t *testing.T) {
+
+	ta := require.New(t)
+
+	_, err := NewHMACSigner(3, map[uint32][]byte{1: []byte("x")})
+	ta.Equal(ErrUnknownKeyID, errors.Cause(err))
+
+	_, err = NewEd25519Signer(1, []byte("short"), nil)
+	ta.Error(err)
+	_, err = NewEd25519Signer(1, nil, map[uint32]ed25519.PublicKey{1: []byte("short")})
+	ta.Error(err)
+
+	_, err = Signed(U8{}, nil)
+	ta.Error(err)
+
+	hs, err := NewHMACSigner(1, map[uint32][]byte{1: []byte("k")})
+	ta.NoError(err)
+	m, err := Signed(String16{}, hs)
+	ta.NoError(err)
+
+	b := m.Encode("abc")
+	_, _, err = m.DecodeChecked(b[:len(b)-1])
+	ta.Equal(ErrShortBuffer, errors.Cause(err))
+
+	b[2] = 'x'
+	testPanic(t, func() { m.Decode(b) }, "tampered")
+	_, err = NewReader(b).Read(m)
+	ta.Equal(ErrAuthFailed, errors.Cause(err))
+}
+
+func TestSignedCompose(t *testing.T) {
+
+	ta := require.New(t)
+
+	hs, err := NewHMACSigner(1, map[uint32][]byte{1: []byte("k")})
+	ta.NoError(err)
+
+	// fixed size: usable with Search
+	m, err := Signed(U16{}, hs)
+	ta.NoError(err)
+
+	buf := encodeAll(m, uint16(1), uint16(5), uint16(0x100))
+	i, found, err := SearchExact(buf, m, uint16(0x100))
+	ta.NoError(err)
+	ta.True(found)
+	ta.Equal(2, i)
+
+	// var-length: in an Array
+	ms, err := Signed(String16{}, hs)
+	ta.NoError(err)
+	strs := []string{"x", "", "yz"}
+	a, err := NewArray(ms, strs, 2)
+	ta.NoError(err)
+	for i, s := range strs {
+		ta.Equal(s, a.Get(i))
+	}
+}
+
+func TestSignedCorruptedInnerSize(t *testing.T) {
+
+	ta := require.New(t)
+
+	s, err := NewHMACSigner(1, map[uint32][]byte{1: []byte("key")})
+	ta.NoError(err)
+	cm, err := Compressed(String16{}, Flate, 1)
+	ta.NoError(err)
+	m, err := Signed(cm, s)
+	ta.NoError(err)
+
+	badPrefix := bytes.Repeat([]byte{0xff}, 11)
+	_, err = m.NeededSizeChecked(badPrefix)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+	testPanic(t, func() { m.NeededSize(badPrefix) }, "corrupted inner prefix")
+
+	_, _, err = m.DecodeChecked(badPrefix)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+	testPanic(t, func() { m.Decode(badPrefix) }, "corrupted inner prefix")
+
+	_, err = NewReader(badPrefix).Read(m)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+	_, _, err = NewDecoder(m).Feed(badPrefix)
+	ta.Equal(ErrCorrupted, errors.Cause(err))
+}
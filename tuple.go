@@ -0,0 +1,556 @@
+package qcodec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// Type codes of tuple components, compatible with FoundationDB tuple layer.
+// A descending component uses the bitwise complement of the code.
+const (
+	tupleNil     = 0x00
+	tupleBytes   = 0x01
+	tupleString  = 0x02
+	tupleIntZero = 0x14
+	tupleFloat32 = 0x20
+	tupleFloat64 = 0x21
+	tupleFalse   = 0x26
+	tupleTrue    = 0x27
+)
+
+// Tuple is a list of components that is encoded into a memcomparable key:
+// the bytes order of packed tuples is the same as the order of tuples,
+// compared component by component.
+//
+// Supported component types are nil, []byte, string, bool, float32, float64
+// and all integer types.
+// A component wrapped with Desc is sorted in descending order.
+// nil is always the least, with or without Desc.
+//
+// The encoding is in the style of FoundationDB tuple layer: a type code
+// followed by the payload.
+// Integers are big-endian with variable length.
+// Floats have the sign bit flipped, or all bits flipped if negative.
+// Strings and bytes have 0x00 escaped as 0x00 0xff and are terminated with
+// 0x00.
+//
+// A descending component has every byte complemented, except that strings and
+// bytes have 0xff escaped as 0xff 0x00 after complementing and are terminated
+// with 0xff 0xff.
+type Tuple []interface{}
+
+// Descending wraps a tuple component to sort in descending order.
+type Descending struct {
+	Value interface{}
+}
+
+// Desc marks a tuple component v to sort in descending order.
+func Desc(v interface{}) Descending {
+	return Descending{Value: v}
+}
+
+// Pack encodes the tuple into a memcomparable key.
+// The packed tuple is a prefix of any packed tuple it is a prefix of.
+// It panics if there is a component of unsupported type.
+func (t Tuple) Pack() []byte {
+	var b []byte
+	for _, v := range t {
+		b = appendTupleItem(b, v)
+	}
+	return b
+}
+
+// Range returns the range [begin, end) of packed keys of all tuples that have
+// t as a strict prefix.
+func (t Tuple) Range() ([]byte, []byte) {
+	p := t.Pack()
+
+	begin := append(append([]byte{}, p...), 0x00)
+	end := append(append([]byte{}, p...), 0xff)
+	return begin, end
+}
+
+// UnpackTuple decodes a packed Tuple.
+// Integers are decoded as int64, or uint64 if it does not fit in int64.
+// A descending component is decoded as a Descending.
+func UnpackTuple(b []byte) (Tuple, error) {
+	t := Tuple{}
+	for len(b) > 0 {
+		n, v, err := decodeTupleItem(b)
+		if err != nil {
+			if errors.Cause(err) == ErrShortBuffer {
+				return nil, errors.Wrapf(ErrMalformed, "truncated tuple: %v", err)
+			}
+			return nil, err
+		}
+		t = append(t, v)
+		b = b[n:]
+	}
+	return t, nil
+}
+
+func appendTupleItem(b []byte, v interface{}) []byte {
+
+	if d, ok := v.(Descending); ok {
+		if d.Value == nil {
+			// nil has only one value and is always the least.
+			return append(b, tupleNil)
+		}
+		if _, ok := d.Value.(Descending); ok {
+			panic(errors.Errorf("nested Desc"))
+		}
+
+		start := len(b)
+		b = appendTupleItem(b, d.Value)
+
+		if b[start] == tupleBytes || b[start] == tupleString {
+			payload := append([]byte{}, b[start+1:len(b)-1]...)
+			payload = unescapeTupleBytes(payload)
+
+			b = append(b[:start], ^b[start])
+			for _, c := range payload {
+				c = ^c
+				b = append(b, c)
+				if c == 0xff {
+					b = append(b, 0x00)
+				}
+			}
+			return append(b, 0xff, 0xff)
+		}
+
+		for i := start; i < len(b); i++ {
+			b[i] = ^b[i]
+		}
+		return b
+	}
+
+	if v == nil {
+		return append(b, tupleNil)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		b = append(b, tupleString)
+		return appendTupleBytes(b, []byte(rv.String()))
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b = append(b, tupleBytes)
+			return appendTupleBytes(b, rv.Bytes())
+		}
+	case reflect.Bool:
+		if rv.Bool() {
+			return append(b, tupleTrue)
+		}
+		return append(b, tupleFalse)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := rv.Int()
+		if i >= 0 {
+			return appendTupleUint(b, uint64(i))
+		}
+		return appendTupleNegInt(b, uint64(-i))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return appendTupleUint(b, rv.Uint())
+	case reflect.Float32:
+		u := math.Float32bits(float32(rv.Float()))
+		if u&(1<<31) != 0 {
+			u = ^u
+		} else {
+			u |= 1 << 31
+		}
+		b = append(b, tupleFloat32)
+		return append(b, byte(u>>24), byte(u>>16), byte(u>>8), byte(u))
+	case reflect.Float64:
+		u := math.Float64bits(rv.Float())
+		if u&(1<<63) != 0 {
+			u = ^u
+		} else {
+			u |= 1 << 63
+		}
+		b = append(b, tupleFloat64)
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], u)
+		return append(b, buf[:]...)
+	}
+
+	panic(errors.Wrapf(ErrUnknownEltType, "tuple component: %T", v))
+}
+
+func appendTupleBytes(b []byte, s []byte) []byte {
+	for _, c := range s {
+		b = append(b, c)
+		if c == 0x00 {
+			b = append(b, 0xff)
+		}
+	}
+	return append(b, 0x00)
+}
+
+func unescapeTupleBytes(s []byte) []byte {
+	return bytes.ReplaceAll(s, []byte{0x00, 0xff}, []byte{0x00})
+}
+
+// appendTupleUint appends a non-negative integer.
+func appendTupleUint(b []byte, u uint64) []byte {
+	n := uintLen(u)
+	b = append(b, byte(tupleIntZero+n))
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, byte(u>>(8*uint(i))))
+	}
+	return b
+}
+
+// appendTupleNegInt appends a negative integer by its absolute value a, in
+// one's complement.
+func appendTupleNegInt(b []byte, a uint64) []byte {
+	n := uintLen(a)
+	b = append(b, byte(tupleIntZero-n))
+	x := ^a
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, byte(x>>(8*uint(i))))
+	}
+	return b
+}
+
+// uintLen returns the number of bytes to store u.
+func uintLen(u uint64) int {
+	n := 0
+	for u > 0 {
+		n++
+		u >>= 8
+	}
+	return n
+}
+
+// decodeTupleItem decodes one tuple component at the start of b.
+// It returns the number of bytes consumed.
+// The error wraps ErrShortBuffer if the component is truncated, or
+// ErrMalformed if it is invalid.
+func decodeTupleItem(b []byte) (int, interface{}, error) {
+
+	code := b[0]
+	if code >= 0x80 {
+		n, v, err := decodeTupleDesc(b)
+		if err != nil {
+			return 0, nil, err
+		}
+		return n, Desc(v), nil
+	}
+
+	switch {
+	case code == tupleNil:
+		return 1, nil, nil
+
+	case code == tupleBytes || code == tupleString:
+		var s []byte
+		i := 1
+		for {
+			if i >= len(b) {
+				return 0, nil, errors.Wrapf(ErrShortBuffer, "unterminated tuple string")
+			}
+			if b[i] == 0x00 {
+				if i+1 < len(b) && b[i+1] == 0xff {
+					s = append(s, 0x00)
+					i += 2
+					continue
+				}
+				break
+			}
+			s = append(s, b[i])
+			i++
+		}
+		if code == tupleString {
+			return i + 1, string(s), nil
+		}
+		if s == nil {
+			s = []byte{}
+		}
+		return i + 1, s, nil
+
+	case code >= tupleIntZero-8 && code <= tupleIntZero+8:
+		n := int(code) - tupleIntZero
+		neg := n < 0
+		if neg {
+			n = -n
+		}
+		if len(b) < 1+n {
+			return 0, nil, errors.Wrapf(ErrShortBuffer, "tuple int: need: %d, got: %d", 1+n, len(b))
+		}
+
+		var u uint64
+		for _, c := range b[1 : 1+n] {
+			u = u<<8 | uint64(c)
+		}
+
+		if neg {
+			u = ^u
+			if n < 8 {
+				u &= 1<<(8*uint(n)) - 1
+			}
+			return 1 + n, -int64(u), nil
+		}
+		if u > math.MaxInt64 {
+			return 1 + n, u, nil
+		}
+		return 1 + n, int64(u), nil
+
+	case code == tupleFloat32:
+		if len(b) < 5 {
+			return 0, nil, errors.Wrapf(ErrShortBuffer, "tuple float32: need: 5, got: %d", len(b))
+		}
+		u := binary.BigEndian.Uint32(b[1:])
+		if u&(1<<31) != 0 {
+			u &^= 1 << 31
+		} else {
+			u = ^u
+		}
+		return 5, math.Float32frombits(u), nil
+
+	case code == tupleFloat64:
+		if len(b) < 9 {
+			return 0, nil, errors.Wrapf(ErrShortBuffer, "tuple float64: need: 9, got: %d", len(b))
+		}
+		u := binary.BigEndian.Uint64(b[1:])
+		if u&(1<<63) != 0 {
+			u &^= 1 << 63
+		} else {
+			u = ^u
+		}
+		return 9, math.Float64frombits(u), nil
+
+	case code == tupleFalse:
+		return 1, false, nil
+
+	case code == tupleTrue:
+		return 1, true, nil
+	}
+
+	return 0, nil, errors.Wrapf(ErrMalformed, "unknown tuple type code: %#x", code)
+}
+
+// decodeTupleDesc decodes a descending component.
+func decodeTupleDesc(b []byte) (int, interface{}, error) {
+
+	code := ^b[0]
+
+	if code == tupleBytes || code == tupleString {
+		asc := []byte{code}
+		i := 1
+		for {
+			if i >= len(b) {
+				return 0, nil, errors.Wrapf(ErrShortBuffer, "unterminated tuple string")
+			}
+			if b[i] == 0xff {
+				if i+1 >= len(b) {
+					return 0, nil, errors.Wrapf(ErrShortBuffer, "unterminated tuple string")
+				}
+				if b[i+1] == 0xff {
+					break
+				}
+				if b[i+1] != 0x00 {
+					return 0, nil, errors.Wrapf(ErrMalformed, "invalid tuple string escape: 0xff %#x", b[i+1])
+				}
+				// escaped 0xff, which is complemented 0x00
+				asc = append(asc, 0x00, 0xff)
+				i += 2
+				continue
+			}
+			asc = append(asc, ^b[i])
+			i++
+		}
+		asc = append(asc, 0x00)
+
+		_, v, err := decodeTupleItem(asc)
+		return i + 2, v, err
+	}
+
+	// Other types are prefix-free and at most 9 bytes, thus can be decoded
+	// after complementing.
+	l := len(b)
+	if l > 9 {
+		l = 9
+	}
+	asc := make([]byte, l)
+	for i, c := range b[:l] {
+		asc[i] = ^c
+	}
+	return decodeTupleItem(asc)
+}
+
+// TupleCodec is a Codec of Tuple with a fixed number of components of given
+// types.
+// It decodes every component back to the type it is defined with.
+//
+// Since the encoding is memcomparable, TupleCodec compares encoded values
+// directly by bytes.
+type TupleCodec struct {
+	types []reflect.Type
+	desc  []bool
+}
+
+// NewTupleCodec creates a *TupleCodec with one zero value for every
+// component, e.g.:
+//     NewTupleCodec(uint32(0), "", Desc(int64(0)))
+// A component wrapped with Desc is sorted in descending order.
+func NewTupleCodec(zeros ...interface{}) (*TupleCodec, error) {
+
+	c := &TupleCodec{}
+	for _, z := range zeros {
+		desc := false
+		if d, ok := z.(Descending); ok {
+			desc = true
+			z = d.Value
+		}
+
+		if z == nil || tupleCategory(reflect.TypeOf(z)) == reflect.Invalid {
+			return nil, errors.Wrapf(ErrUnknownEltType, "tuple component: %T", z)
+		}
+
+		c.types = append(c.types, reflect.TypeOf(z))
+		c.desc = append(c.desc, desc)
+	}
+	return c, nil
+}
+
+// Encode converts a Tuple or []interface{} to memcomparable bytes.
+// Components should not be wrapped with Desc: the order is defined by
+// TupleCodec.
+// It panics if the number of components is different from TupleCodec, or a
+// component is not of the same category as defined, such as a string for an
+// integer.
+// A nil component is encoded as nil and decoded as the zero value.
+func (c *TupleCodec) Encode(d interface{}) []byte {
+	t := c.tuple(d)
+
+	var b []byte
+	for i, v := range t {
+		if v != nil && tupleCategory(reflect.TypeOf(v)) != tupleCategory(c.types[i]) {
+			panic(errors.Wrapf(ErrUnknownEltType, "tuple component %d: want: %v, got: %T", i, c.types[i], v))
+		}
+		if c.desc[i] {
+			v = Desc(v)
+		}
+		b = appendTupleItem(b, v)
+	}
+	return b
+}
+
+// Decode converts bytes to a Tuple.
+// It returns number bytes consumed and a Tuple with every component of the
+// type defined by TupleCodec.
+func (c *TupleCodec) Decode(b []byte) (int, interface{}) {
+	t := make(Tuple, len(c.types))
+
+	off := 0
+	for i, typ := range c.types {
+		n, v, err := decodeTupleItem(b[off:])
+		if err != nil {
+			panic(err)
+		}
+		if d, ok := v.(Descending); ok {
+			v = d.Value
+		}
+		t[i] = convertTupleItem(v, typ)
+		off += n
+	}
+	return off, t
+}
+
+// Size returns the size in byte after encoding v.
+func (c *TupleCodec) Size(d interface{}) int {
+	return len(c.Encode(d))
+}
+
+// EncodedSize returns size of the encoded Tuple by scanning its components.
+func (c *TupleCodec) EncodedSize(b []byte) int {
+	n := c.NeededSize(b)
+	if n > len(b) {
+		panic(errors.Wrapf(ErrShortBuffer, "tuple: need: %d, got: %d", n, len(b)))
+	}
+	return n
+}
+
+// NeededSize returns the encoded size if all components are present in b.
+// Otherwise it returns len(b) + 1.
+// It panics if a component is malformed.
+func (c *TupleCodec) NeededSize(b []byte) int {
+	return mustNeededSize(c.NeededSizeChecked(b))
+}
+
+// NeededSizeChecked is the same as NeededSize except it returns an error
+// wrapping ErrMalformed if a component has an invalid type code or escape.
+func (c *TupleCodec) NeededSizeChecked(b []byte) (int, error) {
+	off := 0
+	for range c.types {
+		if off >= len(b) {
+			return len(b) + 1, nil
+		}
+		n, _, err := decodeTupleItem(b[off:])
+		if err != nil {
+			if errors.Cause(err) == ErrShortBuffer {
+				return len(b) + 1, nil
+			}
+			return 0, err
+		}
+		off += n
+	}
+	return off, nil
+}
+
+// Compare compares two encoded tuples by bytes.
+func (c *TupleCodec) Compare(a, b []byte) int {
+	return bytes.Compare(a[:c.EncodedSize(a)], b[:c.EncodedSize(b)])
+}
+
+func (c *TupleCodec) tuple(d interface{}) Tuple {
+	var t Tuple
+	switch v := d.(type) {
+	case Tuple:
+		t = v
+	case []interface{}:
+		t = v
+	default:
+		panic(errors.Errorf("expect Tuple but: %T", d))
+	}
+
+	if len(t) != len(c.types) {
+		panic(errors.Errorf("expect %d components but: %d", len(c.types), len(t)))
+	}
+	return t
+}
+
+// tupleCategory returns the kind a tuple component of type t is decoded as.
+// It returns reflect.Invalid if t is not supported.
+func tupleCategory(t reflect.Type) reflect.Kind {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.Int64
+	case reflect.Float32, reflect.Float64, reflect.String, reflect.Bool:
+		return t.Kind()
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return reflect.Slice
+		}
+	}
+	return reflect.Invalid
+}
+
+// convertTupleItem converts a decoded component v to type t.
+func convertTupleItem(v interface{}, t reflect.Type) interface{} {
+	if v == nil {
+		return reflect.Zero(t).Interface()
+	}
+
+	rv := reflect.ValueOf(v)
+	want := tupleCategory(t)
+	got := tupleCategory(rv.Type())
+	if want != got {
+		panic(errors.Wrapf(ErrMalformed, "tuple component: want: %v, got: %T", t, v))
+	}
+	return rv.Convert(t).Interface()
+}
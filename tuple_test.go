@@ -0,0 +1,329 @@
+package qcodec
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTuplePackUnpack(t *testing.T) {
+
+	ta := require.New(t)
+
+	cases := []struct {
+		input Tuple
+		want  []byte
+		// decoded tuple, if it is different from input
+		decoded Tuple
+	}{
+		{Tuple{}, nil, nil},
+		{Tuple{nil}, []byte{0x00}, nil},
+		{Tuple{"ab"}, []byte{0x02, 'a', 'b', 0x00}, nil},
+		{Tuple{"a\x00b"}, []byte{0x02, 'a', 0x00, 0xff, 'b', 0x00}, nil},
+		{Tuple{[]byte{}}, []byte{0x01, 0x00}, nil},
+		{Tuple{[]byte{0x00, 0xff}}, []byte{0x01, 0x00, 0xff, 0xff, 0x00}, nil},
+		{Tuple{int64(0)}, []byte{0x14}, nil},
+		{Tuple{1}, []byte{0x15, 0x01}, Tuple{int64(1)}},
+		{Tuple{uint16(0x1234)}, []byte{0x16, 0x12, 0x34}, Tuple{int64(0x1234)}},
+		{Tuple{int64(-1)}, []byte{0x13, 0xfe}, nil},
+		{Tuple{int64(-0x100)}, []byte{0x12, 0xfe, 0xff}, nil},
+		{Tuple{int64(math.MinInt64)}, []byte{0x0c, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, nil},
+		{Tuple{uint64(math.MaxUint64)}, []byte{0x1c, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, nil},
+		{Tuple{true, false}, []byte{0x27, 0x26}, nil},
+		{Tuple{float32(1)}, []byte{0x20, 0xbf, 0x80, 0x00, 0x00}, nil},
+		{Tuple{float64(-1)}, []byte{0x21, 0x40, 0x0f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, nil},
+		{Tuple{Desc(int64(1))}, []byte{0xea, 0xfe}, nil},
+		{Tuple{Desc("ab")}, []byte{0xfd, 0x9e, 0x9d, 0xff, 0xff}, nil},
+		{Tuple{Desc([]byte{0x00, 0xff})}, []byte{0xfe, 0xff, 0x00, 0x00, 0xff, 0xff}, nil},
+		{Tuple{Desc(nil)}, []byte{0x00}, Tuple{nil}},
+		{Tuple{Desc(true)}, []byte{0xd8}, nil},
+		{Tuple{Desc(float64(-1))}, []byte{0xde, 0xbf, 0xf0, 0, 0, 0, 0, 0, 0}, nil},
+		{Tuple{uint32(7), "x", Desc(int64(-3)), nil}, []byte{0x15, 7, 0x02, 'x', 0x00, 0xec, 0x03, 0x00}, Tuple{int64(7), "x", Desc(int64(-3)), nil}},
+	}
+
+	for i, c := range cases {
+		b := c.input.Pack()
+		ta.Equal(c.want, b, "%d-th: case: %+v", i+1, c)
+
+		want := c.decoded
+		if want == nil {
+			want = c.input
+		}
+
+		got, err := UnpackTuple(b)
+		ta.NoError(err)
+		ta.Equal(want, got, "%d-th: case: %+v", i+1, c)
+	}
+
+	testPanic(t, func() { Tuple{[]int{1}}.Pack() }, "unsupported type")
+	testPanic(t, func() { Tuple{Desc(Desc(1))}.Pack() }, "nested Desc")
+}
+
+func TestTupleUnpackMalformed(t *testing.T) {
+
+	ta := require.New(t)
+
+	cases := [][]byte{
+		{0x02, 'a'},
+		{0x15},
+		{0x0c, 0x01},
+		{0x20, 0x00},
+		{0x21, 0x00},
+		{0x03},
+		{0xfd, 0x9e},
+		{0xfd, 0x9e, 0xff},
+		{0xea},
+	}
+
+	for i, c := range cases {
+		_, err := UnpackTuple(c)
+		ta.Equal(ErrMalformed, errors.Cause(err), "%d-th: case: %v", i+1, c)
+	}
+}
+
+func TestTupleOrder(t *testing.T) {
+
+	ta := require.New(t)
+
+	rnd := rand.New(rand.NewSource(1))
+	strs := []string{"", "\x00", "\x00\x00", "\x00\xff", "\xff", "a", "a\x00", "a\x00b", "ab", "b"}
+	ints := []int64{math.MinInt64, -0x10000, -0x100, -0xff, -1, 0, 1, 0xff, 0x100, math.MaxInt64}
+	floats := []float64{math.Inf(-1), -1e10, -1, -1e-10, 0, 1e-10, 1, 1e10, math.Inf(1)}
+
+	type row struct {
+		a int64
+		s string
+		d int64
+		f float64
+	}
+
+	var rows []row
+	for i := 0; i < 500; i++ {
+		rows = append(rows, row{
+			a: ints[rnd.Intn(len(ints))],
+			s: strs[rnd.Intn(len(strs))],
+			d: ints[rnd.Intn(len(ints))],
+			f: floats[rnd.Intn(len(floats))],
+		})
+	}
+
+	// order: a asc, s desc, d desc, f asc
+	less := func(x, y row) bool {
+		if x.a != y.a {
+			return x.a < y.a
+		}
+		if x.s != y.s {
+			return x.s > y.s
+		}
+		if x.d != y.d {
+			return x.d > y.d
+		}
+		return x.f < y.f
+	}
+
+	pack := func(r row) []byte {
+		return Tuple{r.a, Desc(r.s), Desc(r.d), r.f}.Pack()
+	}
+
+	for i := 0; i < len(rows); i++ {
+		for j := 0; j < len(rows); j += 7 {
+			x, y := rows[i], rows[j]
+			want := 0
+			if less(x, y) {
+				want = -1
+			} else if less(y, x) {
+				want = 1
+			}
+			ta.Equal(want, bytes.Compare(pack(x), pack(y)), "x: %+v, y: %+v", x, y)
+		}
+	}
+
+	// strings of different types and a nested prefix
+
+	keys := [][]byte{
+		Tuple{nil}.Pack(),
+		Tuple{[]byte("z")}.Pack(),
+		Tuple{"a"}.Pack(),
+		Tuple{"a", nil}.Pack(),
+		Tuple{"a", int64(-1)}.Pack(),
+		Tuple{"a", int64(0)}.Pack(),
+		Tuple{"a\x00"}.Pack(),
+		Tuple{int64(-5)}.Pack(),
+		Tuple{float32(-1)}.Pack(),
+		Tuple{float64(-1)}.Pack(),
+		Tuple{false}.Pack(),
+		Tuple{true}.Pack(),
+	}
+	ta.True(sort.SliceIsSorted(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i], keys[j]) < 0
+	}))
+}
+
+func TestTupleRange(t *testing.T) {
+
+	ta := require.New(t)
+
+	begin, end := Tuple{uint32(1), "user"}.Range()
+
+	in := []Tuple{
+		{uint32(1), "user", nil},
+		{uint32(1), "user", int64(-1)},
+		{uint32(1), "user", "", int64(5)},
+		{uint32(1), "user", Desc("zzz")},
+	}
+	out := []Tuple{
+		{uint32(1), "user"},
+		{uint32(1), "users"},
+		{uint32(1), "use"},
+		{uint32(2)},
+		{uint32(0), "user", int64(1)},
+	}
+
+	for _, tp := range in {
+		k := tp.Pack()
+		ta.True(bytes.Compare(begin, k) <= 0 && bytes.Compare(k, end) < 0, "%v", tp)
+	}
+	for _, tp := range out {
+		k := tp.Pack()
+		ta.False(bytes.Compare(begin, k) <= 0 && bytes.Compare(k, end) < 0, "%v", tp)
+	}
+}
+
+func TestTupleCodec(t *testing.T) {
+
+	ta := require.New(t)
+
+	_, err := NewTupleCodec(uint32(0), []int{})
+	ta.Equal(ErrUnknownEltType, errors.Cause(err))
+	_, err = NewTupleCodec(nil)
+	ta.Equal(ErrUnknownEltType, errors.Cause(err))
+
+	c, err := NewTupleCodec(uint32(0), "", Desc(int64(0)), []byte{}, float32(0), true)
+	ta.NoError(err)
+
+	cases := []Tuple{
+		{uint32(0), "", int64(0), []byte{}, float32(0), false},
+		{uint32(7), "abc\x00", int64(-1), []byte{0xff}, float32(-1.5), true},
+		{uint32(math.MaxUint32), "\xff", int64(math.MaxInt64), []byte("x"), float32(3), true},
+	}
+
+	for i, tp := range cases {
+		b := c.Encode(tp)
+		ta.Equal(len(b), c.Size(tp))
+		ta.Equal(len(b), c.EncodedSize(b))
+		ta.Equal(len(b), c.NeededSize(b))
+
+		for j := 0; j < len(b); j++ {
+			ta.True(c.NeededSize(b[:j]) > j, "%d-th: prefix: %d", i+1, j)
+		}
+
+		n, v := c.Decode(append(b, 0x15, 0x01))
+		ta.Equal(len(b), n)
+		ta.Equal(tp, v, "%d-th: case: %v", i+1, tp)
+
+		// []interface{} is accepted too
+		ta.Equal(b, c.Encode([]interface{}(tp)))
+	}
+
+	// descending component
+
+	a := c.Encode(Tuple{uint32(1), "a", int64(5), []byte{}, float32(0), false})
+	b := c.Encode(Tuple{uint32(1), "a", int64(4), []byte{}, float32(0), false})
+	ta.Equal(-1, c.Compare(a, b))
+	ta.True(bytes.Compare(a, b) < 0)
+
+	testPanic(t, func() { c.Encode(Tuple{uint32(1)}) }, "wrong number of components")
+	testPanic(t, func() { c.Encode(1) }, "not a tuple")
+
+	// component type mismatch
+	for _, tp := range []Tuple{
+		{"x", "a", int64(5), []byte{}, float32(0), false},
+		{uint32(1), 1, int64(5), []byte{}, float32(0), false},
+		{uint32(1), "a", int64(5), "b", float32(0), false},
+		{uint32(1), "a", int64(5), []byte{}, float64(0), false},
+		{uint32(1), "a", Desc(int64(5)), []byte{}, float32(0), false},
+	} {
+		func() {
+			defer func() {
+				ta.Equal(ErrUnknownEltType, errors.Cause(recover().(error)), "tuple: %v", tp)
+			}()
+			c.Encode(tp)
+		}()
+	}
+
+	// integers of other widths and nil are accepted
+	_, v := c.Decode(c.Encode(Tuple{7, "a", uint8(5), nil, float32(0), false}))
+	ta.Equal(Tuple{uint32(7), "a", int64(5), []byte(nil), float32(0), false}, v)
+	testPanic(t, func() { c.EncodedSize(a[:3]) }, "short buffer")
+
+	// decode into a wrong type
+	sc, err := NewTupleCodec("")
+	ta.NoError(err)
+	testPanic(t, func() { sc.Decode(Tuple{int64(1)}.Pack()) }, "wrong component type")
+
+	// works with Decoder
+	d := NewDecoder(c)
+	var got []interface{}
+	for _, tp := range cases {
+		for _, x := range c.Encode(tp) {
+			vals, _, err := d.Feed([]byte{x})
+			ta.NoError(err)
+			got = append(got, vals...)
+		}
+	}
+	ta.Equal(len(cases), len(got))
+	for i, tp := range cases {
+		ta.Equal(tp, got[i])
+	}
+}
+
+func TestTupleCodecMalformed(t *testing.T) {
+
+	ta := require.New(t)
+
+	c, err := NewTupleCodec(uint32(0), Desc(""))
+	ta.NoError(err)
+
+	good := c.Encode(Tuple{uint32(1), "a\xff"})
+
+	cases := [][]byte{
+		// unknown type code
+		{0x03},
+		{0x15, 0x01, 0x03},
+		// invalid escape in a descending string
+		{0x15, 0x01, 0xfd, 0x9e, 0xff, 0x01},
+	}
+
+	for i, b := range cases {
+		_, err := c.NeededSizeChecked(b)
+		ta.Equal(ErrMalformed, errors.Cause(err), "%d-th: %v", i+1, b)
+		testPanic(t, func() { c.NeededSize(b) }, "malformed")
+
+		d := NewDecoder(c)
+		vals, _, err := d.Feed(good)
+		ta.NoError(err)
+		ta.Equal(1, len(vals))
+
+		// fed byte by byte, an error is reported, rather than waiting forever
+		err = nil
+		for _, x := range b {
+			_, _, err = d.Feed([]byte{x})
+			if err != nil {
+				break
+			}
+		}
+		ta.Equal(ErrMalformed, errors.Cause(err), "%d-th: %v", i+1, b)
+	}
+
+	// truncated input needs more bytes
+	for j := 0; j < len(good); j++ {
+		n, err := c.NeededSizeChecked(good[:j])
+		ta.NoError(err)
+		ta.Equal(j+1, n)
+	}
+}
@@ -106,3 +106,16 @@ func (m *TypeCodec) Size(d interface{}) int {
 func (m *TypeCodec) EncodedSize(b []byte) int {
 	return m.size
 }
+
+// Compare compares two encoded values.
+// If the encoding is order-preserving, e.g., big-endian unsigned integers, it
+// compares the bytes directly.
+// Otherwise it decodes them and compares field by field.
+func (m *TypeCodec) Compare(a, b []byte) int {
+	if orderPreserving(m) {
+		return bytes.Compare(a[:m.size], b[:m.size])
+	}
+	_, x := m.Decode(a)
+	_, y := m.Decode(b)
+	return compareValues(x, y)
+}
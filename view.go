@@ -0,0 +1,252 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnknownField indicates a field name or index is not found in a View.
+var ErrUnknownField = errors.New("unknown field")
+
+// ViewField is a field of basic type in the encoding of a TypeCodec.
+type ViewField struct {
+	// Name is the dotted path of the field, such as "Pos.X" for a field in a
+	// struct, or "Arr.1" for an element of an array.
+	// It is empty if the type of the TypeCodec is a basic type.
+	Name string
+	// Offset is the offset of the field in the encoded bytes.
+	Offset int
+	// Size is the encoded size of the field.
+	Size int
+	// Kind is the kind of the field, one of bool, fixed size integers, floats
+	// and complexes.
+	Kind reflect.Kind
+}
+
+// View reads and writes fields of values encoded by a TypeCodec, directly in
+// the encoded bytes, without decoding the entire value.
+// Struct fields and array elements are flattened into fields of basic types,
+// and the offset of every field is computed once when creating a View.
+//
+// A field is returned as a value of its basic kind, such as uint32 for a field
+// of "type ID uint32".
+// Blank fields, "_", occupy space but are not accessible.
+type View struct {
+	byteOrder binary.ByteOrder
+	size      int
+	fields    []ViewField
+	byName    map[string]int
+}
+
+// NewView creates a *View of the layout of a TypeCodec.
+func NewView(m *TypeCodec) (*View, error) {
+	v := &View{
+		byteOrder: m.byteOrder,
+		size:      m.size,
+		byName:    make(map[string]int),
+	}
+
+	end, err := v.flatten(m.typ, "", 0, false)
+	if err != nil {
+		return nil, err
+	}
+	if end != m.size {
+		return nil, errors.Wrapf(ErrSizeMismatch, "fields size: %d, type size: %d", end, m.size)
+	}
+	return v, nil
+}
+
+// flatten appends all basic fields of typ at offset and returns the end
+// offset.
+func (v *View) flatten(typ reflect.Type, name string, off int, blank bool) (int, error) {
+	switch typ.Kind() {
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			var err error
+			off, err = v.flatten(f.Type, joinFieldName(name, f.Name), off, blank || f.Name == "_")
+			if err != nil {
+				return 0, err
+			}
+		}
+		return off, nil
+
+	case reflect.Array:
+		for i := 0; i < typ.Len(); i++ {
+			var err error
+			off, err = v.flatten(typ.Elem(), joinFieldName(name, strconv.Itoa(i)), off, blank)
+			if err != nil {
+				return 0, err
+			}
+		}
+		return off, nil
+
+	case reflect.Bool, reflect.Int8, reflect.Uint8,
+		reflect.Int16, reflect.Uint16,
+		reflect.Int32, reflect.Uint32, reflect.Float32,
+		reflect.Int64, reflect.Uint64, reflect.Float64,
+		reflect.Complex64, reflect.Complex128:
+
+		size := int(typ.Size())
+		if !blank {
+			v.byName[name] = len(v.fields)
+			v.fields = append(v.fields, ViewField{
+				Name:   name,
+				Offset: off,
+				Size:   size,
+				Kind:   typ.Kind(),
+			})
+		}
+		return off + size, nil
+	}
+
+	return 0, errors.Wrapf(ErrNotFixedSize, "field: %q, type: %v", name, typ)
+}
+
+func joinFieldName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// Fields returns all accessible fields in order.
+func (v *View) Fields() []ViewField {
+	return append([]ViewField{}, v.fields...)
+}
+
+// Index returns the index of a field by its dotted name.
+func (v *View) Index(name string) (int, bool) {
+	i, ok := v.byName[name]
+	return i, ok
+}
+
+// Get returns the value of the named field in b.
+func (v *View) Get(b []byte, name string) (interface{}, error) {
+	i, ok := v.byName[name]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownField, "name: %s", name)
+	}
+	return v.GetAt(b, i)
+}
+
+// GetAt returns the value of the i-th field in b.
+func (v *View) GetAt(b []byte, i int) (interface{}, error) {
+	f, err := v.field(b, i)
+	if err != nil {
+		return nil, err
+	}
+
+	p := b[f.Offset : f.Offset+f.Size]
+	o := v.byteOrder
+
+	switch f.Kind {
+	case reflect.Bool:
+		return p[0] != 0, nil
+	case reflect.Int8:
+		return int8(p[0]), nil
+	case reflect.Uint8:
+		return p[0], nil
+	case reflect.Int16:
+		return int16(o.Uint16(p)), nil
+	case reflect.Uint16:
+		return o.Uint16(p), nil
+	case reflect.Int32:
+		return int32(o.Uint32(p)), nil
+	case reflect.Uint32:
+		return o.Uint32(p), nil
+	case reflect.Float32:
+		return math.Float32frombits(o.Uint32(p)), nil
+	case reflect.Int64:
+		return int64(o.Uint64(p)), nil
+	case reflect.Uint64:
+		return o.Uint64(p), nil
+	case reflect.Float64:
+		return math.Float64frombits(o.Uint64(p)), nil
+	case reflect.Complex64:
+		return complex(math.Float32frombits(o.Uint32(p)), math.Float32frombits(o.Uint32(p[4:]))), nil
+	}
+	// reflect.Complex128
+	return complex(math.Float64frombits(o.Uint64(p)), math.Float64frombits(o.Uint64(p[8:]))), nil
+}
+
+// Set writes x to the named field in b in place.
+// x must be of the same kind as the field.
+func (v *View) Set(b []byte, name string, x interface{}) error {
+	i, ok := v.byName[name]
+	if !ok {
+		return errors.Wrapf(ErrUnknownField, "name: %s", name)
+	}
+	return v.SetAt(b, i, x)
+}
+
+// SetAt writes x to the i-th field in b in place.
+// x must be of the same kind as the field.
+func (v *View) SetAt(b []byte, i int, x interface{}) error {
+	f, err := v.field(b, i)
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(x)
+	if rv.Kind() != f.Kind {
+		return errors.Wrapf(ErrUnknownEltType, "field: %q, kind: %v, value type: %T", f.Name, f.Kind, x)
+	}
+
+	p := b[f.Offset : f.Offset+f.Size]
+	o := v.byteOrder
+
+	switch f.Kind {
+	case reflect.Bool:
+		p[0] = 0
+		if rv.Bool() {
+			p[0] = 1
+		}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		putUintN(o, p, uint64(rv.Int()))
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		putUintN(o, p, rv.Uint())
+	case reflect.Float32:
+		o.PutUint32(p, math.Float32bits(float32(rv.Float())))
+	case reflect.Float64:
+		o.PutUint64(p, math.Float64bits(rv.Float()))
+	case reflect.Complex64:
+		c := rv.Complex()
+		o.PutUint32(p, math.Float32bits(float32(real(c))))
+		o.PutUint32(p[4:], math.Float32bits(float32(imag(c))))
+	case reflect.Complex128:
+		c := rv.Complex()
+		o.PutUint64(p, math.Float64bits(real(c)))
+		o.PutUint64(p[8:], math.Float64bits(imag(c)))
+	}
+	return nil
+}
+
+// field returns the i-th field and checks b is large enough.
+func (v *View) field(b []byte, i int) (ViewField, error) {
+	if i < 0 || i >= len(v.fields) {
+		return ViewField{}, errors.Wrapf(ErrUnknownField, "index: %d, fields: %d", i, len(v.fields))
+	}
+	if len(b) < v.size {
+		return ViewField{}, errors.Wrapf(ErrShortBuffer, "need: %d, got: %d", v.size, len(b))
+	}
+	return v.fields[i], nil
+}
+
+// putUintN writes the lower len(p) bytes of u to p.
+func putUintN(o binary.ByteOrder, p []byte, u uint64) {
+	switch len(p) {
+	case 1:
+		p[0] = byte(u)
+	case 2:
+		o.PutUint16(p, uint16(u))
+	case 4:
+		o.PutUint32(p, uint32(u))
+	default:
+		o.PutUint64(p, u)
+	}
+}
@@ -0,0 +1,164 @@
+package qcodec
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+type viewID uint16
+
+type viewRecord struct {
+	ID    viewID
+	Ok    bool
+	_     [3]byte
+	Pos   typeXY
+	Tags  [2]int8
+	Score float64
+	C     complex64
+}
+
+func TestView(t *testing.T) {
+
+	ta := require.New(t)
+
+	for _, endian := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+
+		m, err := NewTypeCodec(viewRecord{}, endian)
+		ta.Nil(err)
+
+		v, err := NewView(m)
+		ta.Nil(err)
+
+		wantFields := []ViewField{
+			{"ID", 0, 2, reflectKind(uint16(0))},
+			{"Ok", 2, 1, reflectKind(true)},
+			{"Pos.X", 6, 4, reflectKind(int32(0))},
+			{"Pos.Y", 10, 4, reflectKind(int32(0))},
+			{"Tags.0", 14, 1, reflectKind(int8(0))},
+			{"Tags.1", 15, 1, reflectKind(int8(0))},
+			{"Score", 16, 8, reflectKind(float64(0))},
+			{"C", 24, 8, reflectKind(complex64(0))},
+		}
+		ta.Equal(wantFields, v.Fields())
+
+		rec := viewRecord{
+			ID:    7,
+			Ok:    true,
+			Pos:   typeXY{-1, 2},
+			Tags:  [2]int8{-3, 4},
+			Score: 1.5,
+			C:     complex(1, -2),
+		}
+		b := m.Encode(rec)
+
+		cases := []struct {
+			name string
+			want interface{}
+		}{
+			{"ID", uint16(7)},
+			{"Ok", true},
+			{"Pos.X", int32(-1)},
+			{"Pos.Y", int32(2)},
+			{"Tags.0", int8(-3)},
+			{"Tags.1", int8(4)},
+			{"Score", 1.5},
+			{"C", complex64(complex(1, -2))},
+		}
+
+		for i, c := range cases {
+			got, err := v.Get(b, c.name)
+			ta.Nil(err, "%d-th: %s", i+1, c.name)
+			ta.Equal(c.want, got, "%d-th: %s", i+1, c.name)
+
+			idx, ok := v.Index(c.name)
+			ta.True(ok)
+			got, err = v.GetAt(b, idx)
+			ta.Nil(err)
+			ta.Equal(c.want, got, "%d-th: %s", i+1, c.name)
+		}
+
+		// update in place
+
+		ta.Nil(v.Set(b, "ID", viewID(9)))
+		ta.Nil(v.Set(b, "Ok", false))
+		ta.Nil(v.Set(b, "Pos.Y", int32(-5)))
+		ta.Nil(v.Set(b, "Tags.1", int8(-128)))
+		ta.Nil(v.Set(b, "Score", -2.25))
+		ta.Nil(v.Set(b, "C", complex64(complex(3, 4))))
+
+		_, got := m.Decode(b)
+		ta.Equal(viewRecord{
+			ID:    9,
+			Ok:    false,
+			Pos:   typeXY{-1, -5},
+			Tags:  [2]int8{-3, -128},
+			Score: -2.25,
+			C:     complex(3, 4),
+		}, got)
+	}
+}
+
+func TestViewBasicType(t *testing.T) {
+
+	ta := require.New(t)
+
+	m, err := NewTypeCodec(uint32(0))
+	ta.Nil(err)
+	v, err := NewView(m)
+	ta.Nil(err)
+
+	ta.Equal([]ViewField{{"", 0, 4, reflectKind(uint32(0))}}, v.Fields())
+
+	b := m.Encode(uint32(0x01020304))
+	got, err := v.GetAt(b, 0)
+	ta.Nil(err)
+	ta.Equal(uint32(0x01020304), got)
+}
+
+func TestViewErrors(t *testing.T) {
+
+	ta := require.New(t)
+
+	m, err := NewTypeCodec(viewRecord{})
+	ta.Nil(err)
+	v, err := NewView(m)
+	ta.Nil(err)
+
+	b := m.Encode(viewRecord{})
+
+	_, err = v.Get(b, "Pos")
+	ta.Equal(ErrUnknownField, errors.Cause(err))
+
+	_, err = v.Get(b, "_")
+	ta.Equal(ErrUnknownField, errors.Cause(err))
+
+	_, err = v.GetAt(b, 8)
+	ta.Equal(ErrUnknownField, errors.Cause(err))
+
+	_, err = v.GetAt(b, -1)
+	ta.Equal(ErrUnknownField, errors.Cause(err))
+
+	_, err = v.Get(b[:len(b)-1], "ID")
+	ta.Equal(ErrShortBuffer, errors.Cause(err))
+
+	err = v.Set(b, "Pos.X", int64(1))
+	ta.Equal(ErrUnknownEltType, errors.Cause(err))
+
+	err = v.Set(b, "Nope", int32(1))
+	ta.Equal(ErrUnknownField, errors.Cause(err))
+
+	err = v.Set(b[:3], "ID", uint16(1))
+	ta.Equal(ErrShortBuffer, errors.Cause(err))
+
+	// failed updates do not change b
+	_, got := m.Decode(b)
+	ta.Equal(viewRecord{}, got)
+}
+
+func reflectKind(v interface{}) reflect.Kind {
+	return reflect.TypeOf(v).Kind()
+}